@@ -1,9 +1,16 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"math/big"
+	"math/bits"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type MemorySize uint64
@@ -13,10 +20,164 @@ const (
 	MiB MemorySize = 1 << 20
 	GiB MemorySize = 1 << 30
 	TiB MemorySize = 1 << 40
+	PiB MemorySize = 1 << 50
+	EiB MemorySize = 1 << 60
 )
 
+const (
+	KB MemorySize = 1000
+	MB MemorySize = 1000 * KB
+	GB MemorySize = 1000 * MB
+	TB MemorySize = 1000 * GB
+	PB MemorySize = 1000 * TB
+	EB MemorySize = 1000 * PB
+)
+
+var ErrInvalidMemorySize = errors.New("invalid memory size")
+
+var memorySizeUnits = map[string]MemorySize{
+	"":    1,
+	"b":   1,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+	"pib": PiB,
+	"eib": EiB,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"pb":  PB,
+	"eb":  EB,
+}
+
+// exactUnits are tried largest first when marshalling, so the text always parses back to the same size
+var exactUnits = []struct {
+	suffix string
+	unit   MemorySize
+}{
+	{"EiB", EiB}, {"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB},
+}
+
+// ParseMemorySize parses a size with an optional IEC (KiB ... EiB) or SI (KB ... EB) unit,
+// such as 512MiB, 1.5 GiB, 200MB or 4096. Units are case-insensitive,
+// decimals are allowed and fractional bytes are rounded down.
+func ParseMemorySize(value string) (MemorySize, error) {
+	value = strings.TrimSpace(value)
+
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.') {
+		end++
+	}
+
+	unit, ok := memorySizeUnits[strings.ToLower(strings.TrimSpace(value[end:]))]
+	if end == 0 || !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMemorySize, value)
+	}
+
+	number := value[:end]
+
+	// Fast path for whole numbers, big.Rat keeps decimals exact
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		hi, size := bits.Mul64(n, uint64(unit))
+		if hi != 0 {
+			return 0, fmt.Errorf("%w: %q overflows", ErrInvalidMemorySize, value)
+		}
+
+		return MemorySize(size), nil
+	}
+
+	r, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMemorySize, value)
+	}
+
+	r.Mul(r, new(big.Rat).SetUint64(uint64(unit)))
+	size := new(big.Int).Quo(r.Num(), r.Denom())
+
+	if !size.IsUint64() {
+		return 0, fmt.Errorf("%w: %q overflows", ErrInvalidMemorySize, value)
+	}
+
+	return MemorySize(size.Uint64()), nil
+}
+
+// MarshalText formats the size exactly, using the largest IEC unit that needs at most three decimals
+func (m MemorySize) MarshalText() ([]byte, error) {
+	for _, u := range exactUnits {
+		if m < u.unit {
+			continue
+		}
+
+		// m*1000 overflows for the largest units, divide the 128-bit product instead
+		hi, lo := bits.Mul64(uint64(m), 1000)
+
+		thousandths, rem := bits.Div64(hi, lo, uint64(u.unit))
+		if rem != 0 {
+			continue
+		}
+
+		whole, frac := thousandths/1000, thousandths%1000
+
+		text := strconv.AppendUint(nil, whole, 10)
+		if frac != 0 {
+			text = append(text, '.')
+			text = append(text, strings.TrimRight(fmt.Sprintf("%03d", frac), "0")...)
+		}
+
+		return append(text, u.suffix...), nil
+	}
+
+	return append(strconv.AppendUint(nil, uint64(m), 10), 'B'), nil
+}
+
+// UnmarshalText parses text with ParseMemorySize
+func (m *MemorySize) UnmarshalText(text []byte) error {
+	size, err := ParseMemorySize(string(text))
+	if err != nil {
+		return err
+	}
+
+	*m = size
+
+	return nil
+}
+
+// MarshalJSON encodes the size as a string in the MarshalText format
+func (m MemorySize) MarshalJSON() ([]byte, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON accepts a string parsed by ParseMemorySize or a plain number of bytes, null is a no-op
+func (m *MemorySize) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] != '"' {
+		return m.UnmarshalText(data)
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+
+	return m.UnmarshalText([]byte(text))
+}
+
 func (m MemorySize) String() string {
 	switch {
+	case m >= EiB:
+		return FormatMemorySize(m, EiB, "EiB")
+	case m >= PiB:
+		return FormatMemorySize(m, PiB, "PiB")
 	case m >= TiB:
 		return FormatMemorySize(m, TiB, "TiB")
 	case m >= GiB:
@@ -40,3 +201,107 @@ func FormatMemorySize(m MemorySize, unit MemorySize, suffix string) string {
 
 	return UnsafeString(slices.Clip(bytes))
 }
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// maxMemorySizePrecision keeps the scaled value of the largest size within 64 bits
+const maxMemorySizePrecision = 9
+
+// MemorySizeFormatter formats sizes for humans with a fixed number of decimals.
+// Rounding is done on the exact integer value, half away from zero, and never depends on the locale.
+//
+//	f := utils.MemorySizeFormatter{Precision: 1, Space: true}
+//	f.Format(1536 * utils.MiB)                        // 1.5 GiB
+//	f.FormatRate(uploaded, time.Since(start))         // 12.3 MiB/s
+//	utils.MemorySizeFormatter{SI: true}.Format(1500)  // 2kB
+type MemorySizeFormatter struct {
+	// Precision is the number of decimals, capped at 9. Plain bytes never have decimals.
+	Precision int
+	// SI selects 1000-based units (kB, MB ... EB) instead of 1024-based ones (KiB, MiB ... EiB)
+	SI bool
+	// Space separates the number from the unit
+	Space bool
+}
+
+// Format picks the largest unit not exceeding m, moving up a unit when rounding reaches it
+func (f MemorySizeFormatter) Format(m MemorySize) string {
+	base, units := uint64(KiB), iecUnits
+	if f.SI {
+		base, units = uint64(KB), siUnits
+	}
+
+	precision := min(max(f.Precision, 0), maxMemorySizePrecision)
+
+	exp, unit := 0, uint64(1)
+	for exp < len(units)-1 && uint64(m)/unit >= base {
+		exp++
+		unit *= base
+	}
+
+	if exp == 0 {
+		precision = 0
+	}
+
+	scale := uint64(1)
+	for range precision {
+		scale *= 10
+	}
+
+	scaled := roundedDiv(uint64(m), scale, unit)
+
+	// 1023.96KiB rounds to 1024.0KiB, which reads better as 1.0MiB
+	if exp < len(units)-1 && scaled >= base*scale {
+		exp++
+		unit *= base
+		scaled = roundedDiv(uint64(m), scale, unit)
+	}
+
+	b := make([]byte, 0, 32)
+	b = strconv.AppendUint(b, scaled/scale, 10)
+
+	if precision > 0 {
+		frac := strconv.FormatUint(scaled%scale, 10)
+		b = append(b, '.')
+		b = append(b, strings.Repeat("0", precision-len(frac))...)
+		b = append(b, frac...)
+	}
+
+	if f.Space {
+		b = append(b, ' ')
+	}
+
+	return string(append(b, units[exp]...))
+}
+
+// FormatRate formats the throughput of transferring n bytes in elapsed, such as 12.3 MiB/s.
+// Rates above the largest MemorySize are capped to it.
+func (f MemorySizeFormatter) FormatRate(n MemorySize, elapsed time.Duration) string {
+	var rate MemorySize
+
+	if elapsed > 0 {
+		hi, lo := bits.Mul64(uint64(n), uint64(time.Second))
+		if hi >= uint64(elapsed) {
+			rate = math.MaxUint64
+		} else {
+			q, _ := bits.Div64(hi, lo, uint64(elapsed))
+			rate = MemorySize(q)
+		}
+	}
+
+	return f.Format(rate) + "/s"
+}
+
+// roundedDiv returns m*scale/unit rounded half away from zero, using 128 bit intermediates
+func roundedDiv(m, scale, unit uint64) uint64 {
+	hi, lo := bits.Mul64(m, scale)
+	q, rem := bits.Div64(hi, lo, unit)
+
+	if rem >= unit-rem {
+		q++
+	}
+
+	return q
+}
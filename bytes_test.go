@@ -1,7 +1,10 @@
 package utils_test
 
 import (
+	"encoding/json"
+	"flag"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -174,3 +177,167 @@ func TestMemorySize_Constants(t *testing.T) {
 	req.Equal(utils.MiB*1024, utils.GiB)
 	req.Equal(utils.GiB*1024, utils.TiB)
 }
+
+func TestParseMemorySize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected utils.MemorySize
+		wantErr  bool
+	}{
+		{name: "bare bytes", value: "1024", expected: utils.KiB},
+		{name: "bytes suffix", value: "12B", expected: 12},
+		{name: "mebibytes", value: "512MiB", expected: 512 * utils.MiB},
+		{name: "space and case", value: " 2 gib ", expected: 2 * utils.GiB},
+		{name: "tebibytes", value: "1TiB", expected: utils.TiB},
+		{name: "si megabytes", value: "200MB", expected: 200 * utils.MB},
+		{name: "si kilobytes lower case", value: "3kb", expected: 3000},
+		{name: "decimal", value: "1.5GiB", expected: utils.GiB + 512*utils.MiB},
+		{name: "leading dot", value: ".5KiB", expected: 512},
+		{name: "fractional bytes rounded down", value: "1.7B", expected: 1},
+		{name: "decimal si", value: "2.25 TB", expected: 2250 * utils.GB},
+		{name: "two dots", value: "1.2.3KiB", wantErr: true},
+		{name: "exponent", value: "1e3", wantErr: true},
+		{name: "decimal overflow", value: "16777216.5TiB", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+		{name: "unit only", value: "MiB", wantErr: true},
+		{name: "unknown unit", value: "3 parsecs", wantErr: true},
+		{name: "negative", value: "-1KiB", wantErr: true},
+		{name: "overflow", value: "20000000TiB", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := utils.ParseMemorySize(tc.value)
+			if tc.wantErr {
+				require.ErrorIs(t, err, utils.ErrInvalidMemorySize)
+
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, size)
+		})
+	}
+}
+
+func TestMemorySize_TextAndJSON(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	for size, text := range map[utils.MemorySize]string{
+		0:                           "0B",
+		1023:                        "1023B",
+		1536:                        "1.5KiB",
+		1537:                        "1537B",
+		512 * utils.MiB:             "512MiB",
+		utils.GiB + 256*utils.MiB:   "1.25GiB",
+		3 * utils.TiB:               "3TiB",
+		200 * utils.MB:              "195312.5KiB",
+		utils.PiB + utils.PiB/8:     "1.125PiB",
+		utils.EiB + utils.EiB/8:     "1.125EiB",
+		3 * utils.EiB / 2:           "1.5EiB",
+		15*utils.EiB + utils.EiB/2:  "15.5EiB",
+		utils.MemorySize(1<<64 - 1): "18446744073709551615B",
+	} {
+		marshalled, err := size.MarshalText()
+		req.NoError(err)
+		req.Equal(text, string(marshalled))
+
+		var parsed utils.MemorySize
+		req.NoError(parsed.UnmarshalText(marshalled))
+		req.Equal(size, parsed)
+	}
+
+	type config struct {
+		Cache utils.MemorySize  `json:"cache"`
+		Limit utils.MemorySize  `json:"limit"`
+		Max   *utils.MemorySize `json:"max"`
+	}
+
+	var cfg config
+	req.NoError(json.Unmarshal([]byte(`{"cache":"1.5GiB","limit":4096,"max":null}`), &cfg))
+	req.Equal(utils.GiB+512*utils.MiB, cfg.Cache)
+	req.Equal(4*utils.KiB, cfg.Limit)
+	req.Nil(cfg.Max)
+
+	data, err := json.Marshal(cfg)
+	req.NoError(err)
+	req.JSONEq(`{"cache":"1.5GiB","limit":"4KiB","max":null}`, string(data))
+
+	req.Error(json.Unmarshal([]byte(`{"cache":"lots"}`), &cfg))
+	req.Error(json.Unmarshal([]byte(`{"cache":-1}`), &cfg))
+}
+
+func TestMemorySize_Flag(t *testing.T) {
+	t.Parallel()
+
+	var size utils.MemorySize
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.TextVar(&size, "cache", utils.MemorySize(0), "cache size")
+
+	require.NoError(t, fs.Parse([]string{"-cache", "256MB"}))
+	require.Equal(t, 256*utils.MB, size)
+}
+
+func TestMemorySizeFormatter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		formatter utils.MemorySizeFormatter
+		size      utils.MemorySize
+		expected  string
+	}{
+		{name: "default", size: 1536 * utils.MiB, expected: "2GiB"},
+		{name: "precision", formatter: utils.MemorySizeFormatter{Precision: 1}, size: 1536 * utils.MiB, expected: "1.5GiB"},
+		{name: "space", formatter: utils.MemorySizeFormatter{Precision: 2, Space: true}, size: 1536 * utils.MiB, expected: "1.50 GiB"},
+		{name: "padded fraction", formatter: utils.MemorySizeFormatter{Precision: 3}, size: utils.KiB + 10, expected: "1.010KiB"},
+		{name: "bytes have no decimals", formatter: utils.MemorySizeFormatter{Precision: 2, Space: true}, size: 512, expected: "512 B"},
+		{name: "half away from zero", size: 2*utils.KiB + 512, expected: "3KiB"},
+		{name: "below half", size: 2*utils.KiB + 511, expected: "2KiB"},
+		{name: "rounding moves up a unit", formatter: utils.MemorySizeFormatter{Precision: 1}, size: utils.MiB - 1, expected: "1.0MiB"},
+		{name: "si", formatter: utils.MemorySizeFormatter{SI: true, Precision: 1}, size: 1500, expected: "1.5kB"},
+		{name: "si bytes", formatter: utils.MemorySizeFormatter{SI: true}, size: 999, expected: "999B"},
+		{name: "si rounding moves up a unit", formatter: utils.MemorySizeFormatter{SI: true}, size: 999_999, expected: "1MB"},
+		{name: "pebibytes", formatter: utils.MemorySizeFormatter{Precision: 1}, size: 3 * utils.PiB / 2, expected: "1.5PiB"},
+		{name: "exbibytes", formatter: utils.MemorySizeFormatter{Precision: 2}, size: utils.MemorySize(1<<64 - 1), expected: "16.00EiB"},
+		{name: "exabytes", formatter: utils.MemorySizeFormatter{SI: true, Precision: 9}, size: utils.MemorySize(1<<64 - 1), expected: "18.446744074EB"},
+		{name: "precision capped", formatter: utils.MemorySizeFormatter{Precision: 30}, size: utils.KiB, expected: "1.000000000KiB"},
+		{name: "negative precision", formatter: utils.MemorySizeFormatter{Precision: -1}, size: utils.KiB, expected: "1KiB"},
+		{name: "zero", formatter: utils.MemorySizeFormatter{Precision: 1}, expected: "0B"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, tc.formatter.Format(tc.size))
+		})
+	}
+}
+
+func TestMemorySizeFormatter_FormatRate(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	f := utils.MemorySizeFormatter{Precision: 1, Space: true}
+
+	req.Equal("12.3 MiB/s", f.FormatRate(123*utils.MiB, 10*time.Second))
+	req.Equal("500 B/s", f.FormatRate(250, 500*time.Millisecond))
+	req.Equal("0 B/s", f.FormatRate(utils.GiB, 0))
+
+	// computed without floats, rates past the largest size are capped
+	req.Equal("7.5 EiB/s", f.FormatRate(15*utils.EiB, 2*time.Second))
+	req.Equal("16.0 EiB/s", f.FormatRate(15*utils.EiB, time.Nanosecond))
+}
+
+func TestMemorySize_StringLargeUnits(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "2PiB", (2 * utils.PiB).String())
+	require.Equal(t, "16EiB", utils.MemorySize(1<<64-1).String())
+}
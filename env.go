@@ -1,14 +1,17 @@
 package utils
 
 import (
+	"errors"
+	"log/slog"
+	"net/netip"
+	"net/url"
 	"os"
-	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 	"unsafe"
-
-	"github.com/joho/godotenv"
 )
 
 const (
@@ -22,6 +25,79 @@ type EnvProvider interface {
 	Set(key, value string)
 }
 
+// EnvTypeKey is the EnvParseError.Type reported for keys decoded by ParseKey
+const EnvTypeKey = "key"
+
+// envTypeNames are the EnvParseError.Type of types whose Go name is not a good fit for an error message,
+// every other type is reported by its Go name (int8, float64, bool, ...)
+var envTypeNames = map[reflect.Type]string{
+	reflect.TypeFor[time.Duration]():     "duration",
+	reflect.TypeFor[time.Time]():         "time",
+	reflect.TypeFor[*time.Location]():    "location",
+	reflect.TypeFor[*url.URL]():          "url",
+	reflect.TypeFor[netip.Addr]():        "ip",
+	reflect.TypeFor[[]netip.Addr]():      "ip list",
+	reflect.TypeFor[[]netip.Prefix]():    "cidr list",
+	reflect.TypeFor[map[string]string](): "map",
+	reflect.TypeFor[slog.Level]():        "log level",
+	reflect.TypeFor[MemorySize]():        "size",
+	reflect.TypeFor[[]string]():          "list",
+	reflect.TypeFor[[]byte]():            EnvTypeKey,
+	reflect.TypeFor[[][]byte]():          EnvTypeKey + " list",
+}
+
+// EnvParseError is returned when an environment variable is set but cannot be parsed into the expected type.
+// Value holds the raw value, Error never includes it since it may be a credential.
+type EnvParseError struct {
+	Err   error
+	Key   string
+	Value string
+	Type  string
+}
+
+func (e *EnvParseError) Error() string {
+	return "invalid " + e.Type + " value for " + e.Key + ": " + redactCause(e.Err)
+}
+
+// quotedInput matches the input parsers quote in their errors, e.g. ParseAddr("x"): or level string "x":
+var quotedInput = regexp.MustCompile(`(: | )?\(?"(?:[^"\\]|\\.)*"\)?`)
+
+// redactCause describes err without the input that was parsed, prefixes added by wrapping errors are kept
+func redactCause(err error) string {
+	message := err.Error()
+
+	var (
+		numErr  *strconv.NumError
+		timeErr *time.ParseError
+	)
+
+	switch {
+	case errors.As(err, &numErr):
+		return strings.TrimSuffix(message, numErr.Error()) + "strconv." + numErr.Func + ": " + numErr.Err.Error()
+	case errors.As(err, &timeErr):
+		layout := "parsing time as " + strconv.Quote(timeErr.Layout)
+		if timeErr.Message != "" {
+			layout += quotedInput.ReplaceAllString(timeErr.Message, "")
+		} else {
+			layout += ": cannot parse as " + strconv.Quote(timeErr.LayoutElem)
+		}
+
+		return strings.TrimSuffix(message, timeErr.Error()) + layout
+	default:
+		return quotedInput.ReplaceAllString(message, "")
+	}
+}
+
+func (e *EnvParseError) Unwrap() error {
+	return e.Err
+}
+
+// EnvLookupProvider is implemented by providers whose lookups can fail, such as reading secrets from files.
+// Lookup*Env and Bind use it when available to report the error instead of panicking.
+type EnvLookupProvider interface {
+	Lookup(key string) (string, bool, error)
+}
+
 type (
 	OSEnvProvider struct {
 		prefix string
@@ -32,13 +108,22 @@ type (
 	}
 )
 
-const (
-	baseEnvFile = ".env"
-)
+// Lookup gets the raw value for key, returning the provider error if it implements EnvLookupProvider
+func (e Env) Lookup(key string) (string, bool, error) {
+	if p, ok := e.EnvProvider.(EnvLookupProvider); ok {
+		return p.Lookup(key)
+	}
+
+	value, exists := e.Get(key)
+
+	return value, exists, nil
+}
 
+// NewEnv returns an Env backed by the process environment, loading the dotenv cascade
+// from the working directory first unless disableDotEnv is set, see NewWatchingEnv for reloadable dotenv files
 func NewEnv(disableDotEnv bool, prefix ...string) Env {
 	if !disableDotEnv {
-		MustLoadEnv()
+		LoadEnv()
 	}
 
 	p := ""
@@ -53,22 +138,25 @@ func NewEnv(disableDotEnv bool, prefix ...string) Env {
 	}
 }
 
-func MustLoadEnv(basePath ...string) {
-	env := baseEnvFile
+// LoadEnv loads the dotenv cascade (see LoadDotEnv) from basePath or the working directory on a best effort basis.
+// Missing files are skipped and parse errors are ignored, use LoadDotEnv to handle them.
+func LoadEnv(basePath ...string) DotEnvReport {
+	var opts DotEnvOptions
 	if len(basePath) > 0 {
-		env = filepath.Join(basePath[0], baseEnvFile)
+		opts.Root = basePath[0]
 	}
 
-	if !FileExists(env) {
-		panic("dotenv file not found: " + env)
-	}
+	// Removed slog.Error to avoid race conditions in parallel tests
+	report, _ := LoadDotEnv(opts)
 
-	if err := godotenv.Load(env); err != nil {
-		// Removed slog.Error to avoid race conditions in parallel tests
-		return
-	}
+	return report
+}
 
-	// Removed slog.Debug to avoid race conditions in parallel tests
+// MustLoadEnv is the former name of LoadEnv.
+//
+// Deprecated: it never panics, despite its name. Use LoadEnv, or LoadDotEnv to handle parse errors.
+func MustLoadEnv(basePath ...string) DotEnvReport {
+	return LoadEnv(basePath...)
 }
 
 func (p OSEnvProvider) Get(key string) (string, bool) {
@@ -88,6 +176,21 @@ func GetStringsEnv(e Env, key string, defaultValue []string) []string {
 		return defaultValue
 	}
 
+	return parseStrings(value)
+}
+
+// LookupStringsEnv Gets a comma separated list from environment variable and reports whether it was set,
+// only provider errors are returned since any value is a valid list
+func LookupStringsEnv(e Env, key string) ([]string, bool, error) {
+	value, exists, err := e.Lookup(key)
+	if err != nil || !exists {
+		return nil, exists, err
+	}
+
+	return parseStrings(value), true, nil
+}
+
+func parseStrings(value string) []string {
 	vals := make([]string, 0)
 
 	for v := range strings.SplitSeq(value, ",") {
@@ -113,100 +216,191 @@ func GetEnv(e Env, key, defaultValue string) string {
 }
 
 func GetFloatEnv[T float32 | float64](e Env, key string, defaultValue T) T {
-	value, exists := e.Get(key)
-	if !exists {
-		return defaultValue
-	}
+	value, exists, err := LookupFloatEnv[T](e, key)
 
-	var v T
-	parsed, err := strconv.ParseFloat(value, int(unsafe.Sizeof(v)*8))
-	if err == nil {
-		return T(parsed)
-	}
-
-	panic("invalid float value: " + value + " " + err.Error())
+	return mustEnv(value, exists, err, defaultValue)
 }
 
 func GetIntEnv[T int | int8 | int16 | int32 | int64](e Env, key string, defaultValue T) T {
-	value, exists := e.Get(key)
-	if !exists {
-		return defaultValue
-	}
+	value, exists, err := LookupIntEnv[T](e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
 
-	var v T
+func GetUintEnv[T uint | uint8 | uint16 | uint32 | uint64](e Env, key string, defaultValue T) T {
+	value, exists, err := LookupUintEnv[T](e, key)
 
-	parsed, err := strconv.ParseInt(value, 10, int(unsafe.Sizeof(v)*8))
-	if err == nil {
-		return T(parsed)
-	}
+	return mustEnv(value, exists, err, defaultValue)
+}
 
-	panic("invalid integer value: " + value + " " + err.Error())
+func GetBoolEnv(e Env, key string, defaultValue bool) bool {
+	value, exists, err := LookupBoolEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
 }
 
-func GetUintEnv[T uint | uint8 | uint16 | uint32 | uint64](e Env, key string, defaultValue T) T {
-	value, exists := e.Get(key)
+// GetDurationEnv Gets a duration from environment variable or returns default
+func GetDurationEnv(e Env, key string, defaultValue time.Duration) time.Duration {
+	value, exists, err := LookupDurationEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+func GetKeyEnv(e Env, k string, defaults []byte) []byte {
+	value, exists, err := LookupKeyEnv(e, k)
+
+	return mustEnv(value, exists, err, defaults)
+}
+
+// mustEnv adapts a Lookup*Env result for the Get*Env helpers,
+// it panics on a parse error and returns the default when the variable is not set
+func mustEnv[T any](value T, exists bool, err error, defaultValue T) T {
+	if err != nil {
+		panic(err)
+	}
+
 	if !exists {
 		return defaultValue
 	}
 
-	var v T
-	parsed, err := strconv.ParseUint(value, 10, int(unsafe.Sizeof(v)*8))
-	if err == nil {
-		return T(parsed)
-	}
+	return value
+}
 
-	panic("invalid unsigned value: " + value + " " + err.Error())
+// LookupFloatEnv Gets a float from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed
+func LookupFloatEnv[T float32 | float64](e Env, key string) (T, bool, error) {
+	return lookupEnv(e, key, typeName[T](), func(value string) (T, error) {
+		var v T
+		parsed, err := strconv.ParseFloat(value, int(unsafe.Sizeof(v)*8))
+
+		return T(parsed), err
+	})
 }
 
-func GetBoolEnv(e Env, key string, defaultValue bool) bool {
-	value, exists := e.Get(key)
-	if !exists {
-		return defaultValue
+// LookupIntEnv Gets a signed integer from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed or overflows T
+func LookupIntEnv[T int | int8 | int16 | int32 | int64](e Env, key string) (T, bool, error) {
+	return lookupEnv(e, key, typeName[T](), func(value string) (T, error) {
+		var v T
+		parsed, err := strconv.ParseInt(value, 10, int(unsafe.Sizeof(v)*8))
+
+		return T(parsed), err
+	})
+}
+
+// LookupUintEnv Gets an unsigned integer from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed or overflows T
+func LookupUintEnv[T uint | uint8 | uint16 | uint32 | uint64](e Env, key string) (T, bool, error) {
+	return lookupEnv(e, key, typeName[T](), func(value string) (T, error) {
+		var v T
+		parsed, err := strconv.ParseUint(value, 10, int(unsafe.Sizeof(v)*8))
+
+		return T(parsed), err
+	})
+}
+
+// LookupBoolEnv Gets a boolean from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed
+func LookupBoolEnv(e Env, key string) (bool, bool, error) {
+	return lookupEnv(e, key, typeName[bool](), strconv.ParseBool)
+}
+
+// LookupDurationEnv Gets a duration from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed
+func LookupDurationEnv(e Env, key string) (time.Duration, bool, error) {
+	return lookupEnv(e, key, typeName[time.Duration](), parseDuration)
+}
+
+// LookupKeyEnv Gets a key decoded by ParseKey from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed. Empty values are treated as not set.
+func LookupKeyEnv(e Env, key string) ([]byte, bool, error) {
+	value, exists, err := e.Lookup(key)
+	if err != nil {
+		return nil, exists, err
 	}
 
-	parsed, err := strconv.ParseBool(value)
-	if err == nil {
-		return parsed
+	if !exists || value == "" {
+		return nil, false, nil
 	}
 
-	panic("invalid boolean value: " + value + " " + err.Error())
+	parsed, err := ParseKey(value)
+	if err != nil {
+		return nil, true, &EnvParseError{Key: key, Value: value, Type: typeName[[]byte](), Err: err}
+	}
+
+	return parsed, true, nil
 }
 
-// GetDurationEnv Gets a duration from environment variable or returns default
-func GetDurationEnv(e Env, key string, defaultValue time.Duration) time.Duration {
-	value, exists := e.Get(key)
+// GetKeysEnv Gets a comma separated list of keys decoded by ParseKey from environment variable or returns a default value.
+// Handy for key rotation, where the first key is the active one.
+func GetKeysEnv(e Env, k string, defaults [][]byte) [][]byte {
+	value, exists, err := LookupKeysEnv(e, k)
 
-	if !exists {
-		return defaultValue
+	return mustEnv(value, exists, err, defaults)
+}
+
+// LookupKeysEnv Gets a comma separated list of keys decoded by ParseKey from environment variable,
+// reports whether it was set and returns an *EnvParseError if any key is malformed. Empty values are treated as not set.
+func LookupKeysEnv(e Env, key string) ([][]byte, bool, error) {
+	value, exists, err := e.Lookup(key)
+	if err != nil {
+		return nil, exists, err
+	}
+
+	values := parseStrings(value)
+	if !exists || len(values) == 0 {
+		return nil, false, nil
+	}
+
+	parsed, err := listParser(ParseKey)(value)
+	if err != nil {
+		return nil, true, &EnvParseError{Key: key, Value: value, Type: typeName[[][]byte](), Err: err}
 	}
 
+	return parsed, true, nil
+}
+
+// parseDuration parses a Go duration string, falling back to whole seconds
+func parseDuration(value string) (time.Duration, error) {
 	if parsed, err := time.ParseDuration(value); err == nil {
-		return parsed
+		return parsed, nil
 	}
 
 	// Try parsing as seconds if not a duration string
 	seconds, err := strconv.ParseInt(value, 10, 64)
-	if err == nil {
-		return time.Duration(seconds) * time.Second
+	if err != nil {
+		return 0, err
 	}
 
-	panic("invalid duration value: " + value + " " + err.Error())
+	return time.Duration(seconds) * time.Second, nil
 }
 
-func GetKeyEnv(e Env, k string, defaults []byte) []byte {
-	value, exists := e.Get(k)
-	if !exists {
-		return defaults
-	}
+func lookupEnv[T any](e Env, key, typ string, parse func(string) (T, error)) (T, bool, error) {
+	value, exists, err := e.Lookup(key)
+	if err != nil || !exists {
+		var empty T
 
-	if value == "" {
-		return defaults
+		return empty, exists, err
 	}
 
-	parsed, err := ParseKey(value)
+	parsed, err := parse(value)
 	if err != nil {
-		panic("invalid key: " + value + " " + err.Error())
+		var empty T
+
+		return empty, true, &EnvParseError{Key: key, Value: value, Type: typ, Err: err}
+	}
+
+	return parsed, true, nil
+}
+
+func typeName[T any]() string {
+	return envTypeName(reflect.TypeFor[T]())
+}
+
+func envTypeName(t reflect.Type) string {
+	if name, ok := envTypeNames[t]; ok {
+		return name
 	}
 
-	return parsed
+	return t.String()
 }
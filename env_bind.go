@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"encoding"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	TagEnv      = "env"
+	TagDefault  = "default"
+	TagRequired = "required"
+	TagPrefix   = "prefix"
+)
+
+var (
+	ErrBindTarget      = errors.New("bind target must be a non-nil pointer to a struct")
+	ErrMissingEnv      = errors.New("required environment variable is not set")
+	ErrUnsupportedType = errors.New("unsupported field type")
+)
+
+// BindFieldError describes a single struct field that could not be bound
+type BindFieldError struct {
+	Err   error
+	Field string
+	Key   string
+}
+
+func (e *BindFieldError) Error() string {
+	return "env " + e.Key + " (" + e.Field + "): " + e.Err.Error()
+}
+
+func (e *BindFieldError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	bytesType           = reflect.TypeFor[[]byte]()
+	stringsType         = reflect.TypeFor[[]string]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Bind fills the struct pointed to by dst from environment variables described by struct tags.
+//
+//	type Config struct {
+//		Host    string        `env:"HOST" default:"localhost"`
+//		Port    uint16        `env:"PORT" required:"true"`
+//		Timeout time.Duration `env:"TIMEOUT" default:"30s"`
+//		Key     []byte        `env:"APP_KEY"`
+//		DB      DBConfig      `prefix:"DB_"`
+//	}
+//
+// Nested structs are walked recursively, prepending their prefix tag to every key inside.
+// Every missing or invalid field is reported, the returned error joins all BindFieldError values.
+func Bind(e Env, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+
+	return errors.Join(bindStruct(e, v.Elem(), "", "")...)
+}
+
+// MustBind is like Bind but panics if any field cannot be bound
+func MustBind(e Env, dst any) {
+	if err := Bind(e, dst); err != nil {
+		panic(err)
+	}
+}
+
+func bindStruct(e Env, v reflect.Value, prefix, path string) []error {
+	var errs []error
+
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, hasName := field.Tag.Lookup(TagEnv)
+		if name == "-" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		fieldPath := path + field.Name
+
+		if !hasName && isNestedStruct(fieldValue) {
+			errs = append(errs, bindStruct(e, fieldValue, prefix+field.Tag.Get(TagPrefix), fieldPath+".")...)
+
+			continue
+		}
+
+		if !hasName {
+			continue
+		}
+
+		key := prefix + name
+
+		if err := bindField(e, fieldValue, field.Tag, key); err != nil {
+			errs = append(errs, &BindFieldError{Field: fieldPath, Key: key, Err: err})
+		}
+	}
+
+	return errs
+}
+
+func isNestedStruct(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	return !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)
+}
+
+func bindField(e Env, v reflect.Value, tag reflect.StructTag, key string) error {
+	value, exists, err := e.Lookup(key)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		def, hasDefault := tag.Lookup(TagDefault)
+		if !hasDefault {
+			if required, _ := strconv.ParseBool(tag.Get(TagRequired)); required {
+				return ErrMissingEnv
+			}
+
+			return nil
+		}
+
+		value = def
+	}
+
+	if err = setValue(v, value); err != nil {
+		if errors.Is(err, ErrUnsupportedType) {
+			return err
+		}
+
+		return &EnvParseError{Key: key, Value: value, Type: envTypeName(v.Type()), Err: err}
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)) //nolint:forcetypeassert
+	}
+
+	switch v.Type() {
+	case durationType:
+		parsed, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(parsed))
+
+		return nil
+	case bytesType:
+		// Empty keys are treated as unset, same as GetKeyEnv
+		if value == "" {
+			return nil
+		}
+
+		parsed, err := ParseKey(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBytes(parsed)
+
+		return nil
+	case stringsType:
+		v.Set(reflect.ValueOf(parseStrings(value)))
+
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(parsed)
+	default:
+		return ErrUnsupportedType
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+type bindDBConfig struct {
+	Host string `env:"HOST" default:"localhost"`
+	Port uint16 `env:"PORT" default:"5432"`
+}
+
+type bindConfig struct {
+	DB       bindDBConfig     `prefix:"DB_"`
+	Name     string           `env:"NAME" required:"true"`
+	Hosts    []string         `env:"HOSTS"`
+	Key      []byte           `env:"KEY"`
+	Timeout  time.Duration    `env:"TIMEOUT" default:"30s"`
+	Limit    utils.MemorySize `env:"LIMIT" default:"1024"`
+	Ratio    float64          `env:"RATIO" default:"0.5"`
+	Workers  int8             `env:"WORKERS"`
+	Debug    bool             `env:"DEBUG"`
+	Ignored  string           `env:"-"`
+	untagged string
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	p := utils.NewTestEnv(t, "BIND_")
+	p.Set("NAME", "app")
+	p.Set("HOSTS", "a, b,,c")
+	p.Set("KEY", "0123456789abcdef0123456789abcdef")
+	p.Set("TIMEOUT", "10")
+	p.Set("WORKERS", "4")
+	p.Set("DEBUG", "true")
+	p.Set("DB_HOST", "db.internal")
+
+	var cfg bindConfig
+	req.NoError(utils.Bind(p, &cfg))
+
+	req.Equal("app", cfg.Name)
+	req.Equal([]string{"a", "b", "c"}, cfg.Hosts)
+	req.Len(cfg.Key, 16)
+	req.Equal(10*time.Second, cfg.Timeout)
+	req.Equal(utils.KiB, cfg.Limit)
+	req.InEpsilon(0.5, cfg.Ratio, 0.0001)
+	req.Equal(int8(4), cfg.Workers)
+	req.True(cfg.Debug)
+	req.Equal("db.internal", cfg.DB.Host)
+	req.Equal(uint16(5432), cfg.DB.Port)
+	req.Empty(cfg.Ignored)
+	req.Empty(cfg.untagged)
+}
+
+func TestBind_AggregatesErrors(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	p := utils.NewTestEnv(t, "BIND_ERR_")
+	p.Set("WORKERS", "300")
+	p.Set("DEBUG", "maybe")
+	p.Set("DB_PORT", "-1")
+
+	var cfg bindConfig
+	err := utils.Bind(p, &cfg)
+	req.Error(err)
+	req.ErrorIs(err, utils.ErrMissingEnv)
+
+	var fieldErr *utils.BindFieldError
+	req.ErrorAs(err, &fieldErr)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	req.True(ok)
+
+	keys := make([]string, 0, len(joined.Unwrap()))
+	for _, e := range joined.Unwrap() {
+		req.ErrorAs(e, &fieldErr)
+		keys = append(keys, fieldErr.Key)
+	}
+
+	req.ElementsMatch([]string{"DB_PORT", "NAME", "WORKERS", "DEBUG"}, keys)
+}
+
+func TestBind_InvalidTarget(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	p := utils.NewTestEnv(t)
+
+	var cfg bindConfig
+	req.ErrorIs(utils.Bind(p, cfg), utils.ErrBindTarget)
+	req.ErrorIs(utils.Bind(p, (*bindConfig)(nil)), utils.ErrBindTarget)
+
+	var n int
+	req.ErrorIs(utils.Bind(p, &n), utils.ErrBindTarget)
+}
+
+func TestBind_UnsupportedType(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	p := utils.NewTestEnv(t)
+	p.Set("BIND_MAP", "a=b")
+
+	var cfg struct {
+		Values map[string]string `env:"BIND_MAP"`
+	}
+
+	err := utils.Bind(p, &cfg)
+	req.ErrorIs(err, utils.ErrUnsupportedType)
+}
+
+func TestMustBind(t *testing.T) {
+	t.Parallel()
+	p := utils.NewTestEnv(t, "MUST_BIND_")
+
+	var cfg bindConfig
+	require.Panics(t, func() { utils.MustBind(p, &cfg) })
+
+	p.Set("NAME", "app")
+	require.NotPanics(t, func() { utils.MustBind(p, &cfg) })
+	require.Equal(t, "app", cfg.Name)
+}
+
+func TestBind_ParseErrorDetails(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	p := utils.NewTestEnv(t, "BIND_PARSE_")
+	p.Set("NAME", "app")
+	p.Set("RATIO", "half")
+
+	var cfg bindConfig
+	err := utils.Bind(p, &cfg)
+
+	var parseErr *utils.EnvParseError
+	req.ErrorAs(err, &parseErr)
+	req.Equal("RATIO", parseErr.Key)
+	req.Equal("half", parseErr.Value)
+	req.Equal("float64", parseErr.Type)
+}
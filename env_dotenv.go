@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	baseEnvFile = ".env"
+
+	// EnvironmentKey selects the environment specific dotenv files when DotEnvOptions.Environment is empty
+	EnvironmentKey = "APP_ENV"
+)
+
+// DotEnvOptions configures LoadDotEnv
+type DotEnvOptions struct {
+	// Root is the directory searched for dotenv files, defaults to the working directory
+	Root string
+	// Environment selects .env.<environment> files, one of EnvDev, EnvStage, EnvProd or a custom name.
+	// Defaults to the EnvironmentKey variable from the process or the base .env file, then EnvDev.
+	Environment string
+}
+
+// DotEnvReport describes the outcome of LoadDotEnv
+type DotEnvReport struct {
+	Environment string
+	Root        string
+	// Loaded lists the files that were found and loaded, highest precedence first
+	Loaded []string
+}
+
+// DotEnvFiles returns the dotenv cascade for environment, highest precedence first:
+// .env.<environment>.local, .env.local, .env.<environment> and .env
+func DotEnvFiles(environment string) []string {
+	return []string{
+		baseEnvFile + "." + environment + ".local",
+		baseEnvFile + ".local",
+		baseEnvFile + "." + environment,
+		baseEnvFile,
+	}
+}
+
+// LoadDotEnv loads the dotenv cascade into the process environment.
+// Missing files are skipped, variables already present in the process environment are never overridden
+// and earlier files in the cascade take precedence over later ones.
+// On a parse error the report still lists the files loaded before the failure.
+func LoadDotEnv(opts ...DotEnvOptions) (DotEnvReport, error) {
+	var o DotEnvOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	report := DotEnvReport{
+		Root:        o.Root,
+		Environment: o.Environment,
+	}
+
+	if report.Environment == "" {
+		report.Environment = detectEnvironment(o.Root)
+	}
+
+	for _, file := range DotEnvFiles(report.Environment) {
+		path := filepath.Join(o.Root, file)
+		if !FileExists(path) {
+			continue
+		}
+
+		if err := godotenv.Load(path); err != nil {
+			return report, err
+		}
+
+		report.Loaded = append(report.Loaded, path)
+	}
+
+	return report, nil
+}
+
+func detectEnvironment(root string) string {
+	if env, ok := os.LookupEnv(EnvironmentKey); ok && env != "" {
+		return env
+	}
+
+	if values, err := godotenv.Read(filepath.Join(root, baseEnvFile)); err == nil && values[EnvironmentKey] != "" {
+		return values[EnvironmentKey]
+	}
+
+	return EnvDev
+}
+
+// NewEnvWithDotEnv loads the dotenv cascade described by opts and returns an Env backed by the process environment
+func NewEnvWithDotEnv(opts DotEnvOptions, prefix ...string) (Env, DotEnvReport, error) {
+	report, err := LoadDotEnv(opts)
+	if err != nil {
+		return Env{}, report, err
+	}
+
+	return NewEnv(true, prefix...), report, nil
+}
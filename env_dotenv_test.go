@@ -0,0 +1,142 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func writeDotEnv(tb testing.TB, dir string, files map[string]string) {
+	tb.Helper()
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func unsetEnv(tb testing.TB, keys ...string) {
+	tb.Helper()
+	tb.Cleanup(func() {
+		for _, key := range keys {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestDotEnvFiles(t *testing.T) {
+	t.Parallel()
+	require.Equal(t,
+		[]string{".env.production.local", ".env.local", ".env.production", ".env"},
+		utils.DotEnvFiles(utils.EnvProd),
+	)
+}
+
+func TestLoadDotEnv_Cascade(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	unsetEnv(t, "CASCADE_A", "CASCADE_B", "CASCADE_C", "CASCADE_D")
+	writeDotEnv(t, dir, map[string]string{
+		".env":               "CASCADE_A=base\nCASCADE_B=base\nCASCADE_C=base\nCASCADE_D=base\n",
+		".env.staging":       "CASCADE_A=staging\nCASCADE_B=staging\nCASCADE_C=staging\n",
+		".env.local":         "CASCADE_A=local\nCASCADE_B=local\n",
+		".env.staging.local": "CASCADE_A=staging.local\n",
+		".env.production":    "CASCADE_D=production\n",
+	})
+
+	report, err := utils.LoadDotEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvStage})
+	req.NoError(err)
+	req.Equal(utils.EnvStage, report.Environment)
+	req.Equal(dir, report.Root)
+	req.Equal([]string{
+		filepath.Join(dir, ".env.staging.local"),
+		filepath.Join(dir, ".env.local"),
+		filepath.Join(dir, ".env.staging"),
+		filepath.Join(dir, ".env"),
+	}, report.Loaded)
+
+	req.Equal("staging.local", os.Getenv("CASCADE_A"))
+	req.Equal("local", os.Getenv("CASCADE_B"))
+	req.Equal("staging", os.Getenv("CASCADE_C"))
+	req.Equal("base", os.Getenv("CASCADE_D"))
+}
+
+//nolint:paralleltest
+func TestLoadDotEnv_MissingFilesAndDetection(t *testing.T) {
+	req := require.New(t)
+	dir := t.TempDir()
+
+	// Restored on cleanup, an empty value also keeps .env from leaking APP_ENV into other tests
+	t.Setenv(utils.EnvironmentKey, "")
+	unsetEnv(t, "DETECT_VALUE")
+	writeDotEnv(t, dir, map[string]string{
+		".env":            "APP_ENV=production\n",
+		".env.production": "DETECT_VALUE=prod\n",
+	})
+
+	report, err := utils.LoadDotEnv(utils.DotEnvOptions{Root: dir})
+	req.NoError(err)
+	req.Equal(utils.EnvProd, report.Environment)
+	req.Equal([]string{filepath.Join(dir, ".env.production"), filepath.Join(dir, ".env")}, report.Loaded)
+	req.Equal("prod", os.Getenv("DETECT_VALUE"))
+
+	report, err = utils.LoadDotEnv(utils.DotEnvOptions{Root: t.TempDir()})
+	req.NoError(err)
+	req.Equal(utils.EnvDev, report.Environment)
+	req.Empty(report.Loaded)
+}
+
+func TestLoadDotEnv_ParseError(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	unsetEnv(t, "PARSE_ERROR_LOCAL")
+	writeDotEnv(t, dir, map[string]string{
+		".env.local": "PARSE_ERROR_LOCAL=ok\n",
+		".env":       "INVALID_LINE_WITHOUT_EQUALS\n",
+	})
+
+	report, err := utils.LoadDotEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.Error(err)
+	req.Equal([]string{filepath.Join(dir, ".env.local")}, report.Loaded)
+
+	req.NotPanics(func() { _ = utils.LoadEnv(dir) })
+
+	_, _, err = utils.NewEnvWithDotEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.Error(err)
+}
+
+func TestNewEnvWithDotEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	unsetEnv(t, "WITH_DOTENV_PORT")
+	writeDotEnv(t, dir, map[string]string{".env.development": "WITH_DOTENV_PORT=8080\n"})
+
+	env, report, err := utils.NewEnvWithDotEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev}, "WITH_DOTENV_")
+	req.NoError(err)
+	req.Len(report.Loaded, 1)
+	req.Equal(uint16(8080), utils.GetUintEnv[uint16](env, "PORT", 0))
+}
+
+func TestLoadEnv_MissingIsOptional(t *testing.T) {
+	t.Parallel()
+
+	var report utils.DotEnvReport
+
+	require.NotPanics(t, func() { report = utils.LoadEnv(t.TempDir()) })
+	require.Empty(t, report.Loaded)
+
+	// the deprecated name behaves the same
+	require.NotPanics(t, func() { report = utils.MustLoadEnv(t.TempDir()) }) //nolint:staticcheck
+	require.Empty(t, report.Loaded)
+}
@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrEnvCycle  = errors.New("reference cycle")
+	ErrEnvSyntax = errors.New("malformed variable reference")
+)
+
+// EnvExpandError is returned when a value cannot be expanded
+type EnvExpandError struct {
+	Err   error
+	Key   string
+	Chain []string
+}
+
+func (e *EnvExpandError) Error() string {
+	msg := "expanding " + e.Key + ": " + e.Err.Error()
+	if len(e.Chain) > 0 {
+		msg += ": " + strings.Join(e.Chain, " -> ")
+	}
+
+	return msg
+}
+
+func (e *EnvExpandError) Unwrap() error {
+	return e.Err
+}
+
+// ExpandEnvProvider expands ${VAR} and ${VAR:-default} references in values,
+// resolving them through the wrapped provider (so references work across layers).
+//
+//	DATABASE_URL=postgres://${DB_USER}:${DB_PASS}@${DB_HOST:-localhost}
+//
+// An unset reference expands to an empty string, the default is used when the
+// referenced variable is unset or empty and may itself contain references.
+// Use $$ for a literal dollar sign, or list keys that must never be expanded.
+// Bare $VAR is left untouched.
+//
+// Note that godotenv already expands unquoted and double-quoted values while reading
+// dotenv files and does not understand the :- syntax, use single quotes there:
+//
+//	DATABASE_URL='postgres://${DB_USER}@${DB_HOST:-localhost}'
+type ExpandEnvProvider struct {
+	inner   EnvProvider
+	literal map[string]struct{}
+}
+
+func NewExpandEnvProvider(inner EnvProvider, literalKeys ...string) *ExpandEnvProvider {
+	literal := make(map[string]struct{}, len(literalKeys))
+	for _, key := range literalKeys {
+		literal[key] = struct{}{}
+	}
+
+	return &ExpandEnvProvider{
+		inner:   inner,
+		literal: literal,
+	}
+}
+
+// Get panics if the value cannot be expanded, use Lookup to handle the error
+func (p *ExpandEnvProvider) Get(key string) (string, bool) {
+	value, exists, err := p.Lookup(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return value, exists
+}
+
+func (p *ExpandEnvProvider) Set(key, value string) {
+	p.inner.Set(key, value)
+}
+
+func (p *ExpandEnvProvider) Lookup(key string) (string, bool, error) {
+	return p.resolve(key, nil)
+}
+
+// Keys returns the keys of the wrapped provider if it implements EnvLister
+func (p *ExpandEnvProvider) Keys() []string {
+	if lister, ok := p.inner.(EnvLister); ok {
+		return lister.Keys()
+	}
+
+	return nil
+}
+
+func (p *ExpandEnvProvider) resolve(key string, stack []string) (string, bool, error) {
+	if slices.Contains(stack, key) {
+		return "", true, &EnvExpandError{Key: stack[0], Err: ErrEnvCycle, Chain: append(slices.Clone(stack), key)}
+	}
+
+	value, exists, err := (Env{EnvProvider: p.inner}).Lookup(key)
+	if err != nil || !exists {
+		return value, exists, err
+	}
+
+	if _, ok := p.literal[key]; ok {
+		return value, true, nil
+	}
+
+	expanded, err := p.expand(value, append(stack, key))
+	if err != nil {
+		return "", true, err
+	}
+
+	return expanded, true, nil
+}
+
+func (p *ExpandEnvProvider) expand(value string, stack []string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(value))
+
+	for i := 0; i < len(value); {
+		if value[i] != '$' || i+1 == len(value) {
+			_ = b.WriteByte(value[i])
+			i++
+
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			_ = b.WriteByte('$')
+			i += 2
+		case '{':
+			end := closingBrace(value, i+2)
+			if end == -1 {
+				return "", &EnvExpandError{Key: stack[0], Err: ErrEnvSyntax, Chain: []string{value[i:]}}
+			}
+
+			name, def, hasDefault := strings.Cut(value[i+2:end], ":-")
+			if name == "" || strings.ContainsAny(name, "${}") {
+				return "", &EnvExpandError{Key: stack[0], Err: ErrEnvSyntax, Chain: []string{value[i : end+1]}}
+			}
+
+			resolved, _, err := p.resolve(name, stack)
+			if err != nil {
+				return "", err
+			}
+
+			if resolved == "" && hasDefault {
+				if resolved, err = p.expand(def, stack); err != nil {
+					return "", err
+				}
+			}
+
+			_, _ = b.WriteString(resolved)
+			i = end + 1
+		default:
+			_ = b.WriteByte('$')
+			i++
+		}
+	}
+
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the brace closing the reference opened before start, honouring nesting
+func closingBrace(value string, start int) int {
+	depth := 1
+
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
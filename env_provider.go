@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"flag"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvLister is implemented by providers that can enumerate the keys they hold
+type EnvLister interface {
+	Keys() []string
+}
+
+// MapEnvProvider is an in-memory EnvProvider, safe for concurrent use
+type MapEnvProvider struct {
+	env    map[string]string
+	prefix string
+	mu     sync.RWMutex
+}
+
+// NewMapEnvProvider creates a provider backed by a copy of values
+func NewMapEnvProvider(values map[string]string, prefix ...string) *MapEnvProvider {
+	p := ""
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
+
+	env := make(map[string]string, len(values))
+	maps.Copy(env, values)
+
+	return &MapEnvProvider{
+		prefix: p,
+		env:    env,
+	}
+}
+
+// NewDotEnvProvider reads a dotenv file into a MapEnvProvider without touching the process environment
+func NewDotEnvProvider(path string, prefix ...string) (*MapEnvProvider, error) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMapEnvProvider(values, prefix...), nil
+}
+
+// NewFlagEnvProvider exposes the flags explicitly set on fs as environment variables,
+// flag names are upper-cased and dashes replaced with underscores (db-host -> DB_HOST).
+// The prefix is normalized the same way, so "app-" exposes app-db-host as DB_HOST.
+func NewFlagEnvProvider(fs *flag.FlagSet, prefix ...string) *MapEnvProvider {
+	values := make(map[string]string)
+
+	fs.Visit(func(f *flag.Flag) {
+		values[flagEnvName(f.Name)] = f.Value.String()
+	})
+
+	if len(prefix) > 0 {
+		prefix = []string{flagEnvName(prefix[0])}
+	}
+
+	return NewMapEnvProvider(values, prefix...)
+}
+
+func flagEnvName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func (p *MapEnvProvider) Get(key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v, ok := p.env[p.prefix+key]
+
+	return v, ok
+}
+
+func (p *MapEnvProvider) Set(key, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.env[p.prefix+key] = value
+}
+
+// Merge copies values into the provider, overriding existing keys
+func (p *MapEnvProvider) Merge(values map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	maps.Copy(p.env, values)
+}
+
+// Keys returns the keys visible through the prefix, with the prefix stripped
+func (p *MapEnvProvider) Keys() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	keys := make([]string, 0, len(p.env))
+	for k := range p.env {
+		if key, ok := strings.CutPrefix(k, p.prefix); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// Keys returns the process environment keys visible through the prefix, with the prefix stripped
+func (p OSEnvProvider) Keys() []string {
+	environ := os.Environ()
+	keys := make([]string, 0, len(environ))
+
+	for _, kv := range environ {
+		k, _, _ := strings.Cut(kv, "=")
+		if key, ok := strings.CutPrefix(k, p.prefix); ok && key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// EnvLayer is a named source inside a LayeredEnvProvider
+type EnvLayer struct {
+	Provider EnvProvider
+	Name     string
+}
+
+// LayeredEnvProvider stacks several providers, the first layer holding a key wins
+//
+//	provider := utils.NewLayeredEnvProvider(
+//		utils.EnvLayer{Name: "flags", Provider: utils.NewFlagEnvProvider(flag.CommandLine)},
+//		utils.EnvLayer{Name: "os", Provider: utils.OSEnvProvider{}},
+//		utils.EnvLayer{Name: ".env", Provider: dotenv},
+//		utils.EnvLayer{Name: "defaults", Provider: utils.NewMapEnvProvider(defaults)},
+//	)
+type LayeredEnvProvider struct {
+	layers []EnvLayer
+}
+
+func NewLayeredEnvProvider(layers ...EnvLayer) *LayeredEnvProvider {
+	return &LayeredEnvProvider{
+		layers: slices.Clone(layers),
+	}
+}
+
+func (p *LayeredEnvProvider) Get(key string) (string, bool) {
+	for _, layer := range p.layers {
+		if v, ok := layer.Provider.Get(key); ok {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Lookup is like Get but reports errors from layers implementing EnvLookupProvider
+func (p *LayeredEnvProvider) Lookup(key string) (string, bool, error) {
+	for _, layer := range p.layers {
+		if v, exists, err := (Env{EnvProvider: layer.Provider}).Lookup(key); err != nil || exists {
+			return v, exists, err
+		}
+	}
+
+	return "", false, nil
+}
+
+// Set writes to the highest precedence layer, so the value is visible to subsequent Get calls
+func (p *LayeredEnvProvider) Set(key, value string) {
+	if len(p.layers) == 0 {
+		return
+	}
+
+	p.layers[0].Provider.Set(key, value)
+}
+
+// Source returns the name of the layer that supplies key
+func (p *LayeredEnvProvider) Source(key string) (string, bool) {
+	for _, layer := range p.layers {
+		// A layer that failed to resolve the key still owns it
+		if _, exists, _ := (Env{EnvProvider: layer.Provider}).Lookup(key); exists {
+			return layer.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// Keys returns the sorted union of keys from every layer implementing EnvLister
+func (p *LayeredEnvProvider) Keys() []string {
+	seen := make(map[string]struct{})
+
+	for _, layer := range p.layers {
+		lister, ok := layer.Provider.(EnvLister)
+		if !ok {
+			continue
+		}
+
+		for _, key := range lister.Keys() {
+			seen[key] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// Layers returns the configured layers in precedence order
+func (p *LayeredEnvProvider) Layers() []EnvLayer {
+	return slices.Clone(p.layers)
+}
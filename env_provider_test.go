@@ -0,0 +1,164 @@
+package utils_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func TestMapEnvProvider(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	values := map[string]string{"APP_HOST": "localhost", "OTHER": "x"}
+	p := utils.NewMapEnvProvider(values, "APP_")
+
+	v, ok := p.Get("HOST")
+	req.True(ok)
+	req.Equal("localhost", v)
+
+	p.Set("PORT", "8080")
+	req.Equal([]string{"HOST", "PORT"}, p.Keys())
+
+	// the source map is copied
+	req.NotContains(values, "APP_PORT")
+
+	p.Merge(map[string]string{"APP_HOST": "example.com"})
+	v, _ = p.Get("HOST")
+	req.Equal("example.com", v)
+}
+
+func TestNewDotEnvProvider(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".env")
+	req.NoError(os.WriteFile(file, []byte("DOTENV_PROVIDER_KEY=value\n"), 0o600))
+
+	p, err := utils.NewDotEnvProvider(file)
+	req.NoError(err)
+
+	v, ok := p.Get("DOTENV_PROVIDER_KEY")
+	req.True(ok)
+	req.Equal("value", v)
+
+	_, exists := os.LookupEnv("DOTENV_PROVIDER_KEY")
+	req.False(exists)
+
+	_, err = utils.NewDotEnvProvider(filepath.Join(dir, "missing"))
+	req.Error(err)
+}
+
+func TestNewFlagEnvProvider(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_ = fs.String("db-host", "localhost", "")
+	_ = fs.Int("port", 80, "")
+	req.NoError(fs.Parse([]string{"-db-host", "db.internal"}))
+
+	p := utils.NewFlagEnvProvider(fs)
+
+	v, ok := p.Get("DB_HOST")
+	req.True(ok)
+	req.Equal("db.internal", v)
+
+	// flags left at their default are not reported
+	_, ok = p.Get("PORT")
+	req.False(ok)
+}
+
+func TestNewFlagEnvProvider_Prefix(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_ = fs.String("app-db-host", "localhost", "")
+	_ = fs.String("verbose", "", "")
+	req.NoError(fs.Parse([]string{"-app-db-host", "db.internal", "-verbose", "1"}))
+
+	for _, prefix := range []string{"app-", "APP_"} {
+		p := utils.NewFlagEnvProvider(fs, prefix)
+
+		v, ok := p.Get("DB_HOST")
+		req.True(ok, prefix)
+		req.Equal("db.internal", v)
+		req.Equal([]string{"DB_HOST"}, p.Keys())
+
+		_, ok = p.Get("VERBOSE")
+		req.False(ok, prefix)
+	}
+}
+
+func TestLayeredEnvProvider(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	flags := utils.NewMapEnvProvider(map[string]string{"PORT": "9000"})
+	dotenv := utils.NewMapEnvProvider(map[string]string{"PORT": "8000", "HOST": "dotenv.local"})
+	defaults := utils.NewMapEnvProvider(map[string]string{"PORT": "80", "HOST": "localhost", "DEBUG": "false"})
+
+	p := utils.NewLayeredEnvProvider(
+		utils.EnvLayer{Name: "flags", Provider: flags},
+		utils.EnvLayer{Name: ".env", Provider: dotenv},
+		utils.EnvLayer{Name: "defaults", Provider: defaults},
+	)
+	env := utils.Env{EnvProvider: p}
+
+	req.Equal(uint16(9000), utils.GetUintEnv[uint16](env, "PORT", 0))
+	req.Equal("dotenv.local", utils.GetEnv(env, "HOST", ""))
+	req.False(utils.GetBoolEnv(env, "DEBUG", true))
+
+	source, ok := p.Source("PORT")
+	req.True(ok)
+	req.Equal("flags", source)
+
+	source, ok = p.Source("HOST")
+	req.True(ok)
+	req.Equal(".env", source)
+
+	source, ok = p.Source("DEBUG")
+	req.True(ok)
+	req.Equal("defaults", source)
+
+	_, ok = p.Source("MISSING")
+	req.False(ok)
+
+	req.Equal([]string{"DEBUG", "HOST", "PORT"}, p.Keys())
+	req.Len(p.Layers(), 3)
+
+	// Set goes to the highest precedence layer
+	p.Set("DEBUG", "true")
+	req.True(utils.GetBoolEnv(env, "DEBUG", false))
+
+	source, _ = p.Source("DEBUG")
+	req.Equal("flags", source)
+}
+
+func TestLayeredEnvProvider_Empty(t *testing.T) {
+	t.Parallel()
+	p := utils.NewLayeredEnvProvider()
+
+	p.Set("KEY", "value")
+	_, ok := p.Get("KEY")
+	require.False(t, ok)
+	require.Empty(t, p.Keys())
+}
+
+//nolint:paralleltest
+func TestOSEnvProvider_Keys(t *testing.T) {
+	t.Setenv("OS_KEYS_TEST_A", "1")
+	t.Setenv("OS_KEYS_TEST_B", "2")
+
+	p := utils.OSEnvProvider{}
+	keys := p.Keys()
+	require.Contains(t, keys, "OS_KEYS_TEST_A")
+	require.Contains(t, keys, "OS_KEYS_TEST_B")
+}
@@ -0,0 +1,391 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Oudwins/zog"
+)
+
+var (
+	ErrEnvInvalid = errors.New("validation failed")
+
+	errEnvRedacted = errors.New("malformed value")
+)
+
+// redacted replaces the value of a secret in issue messages
+const redacted = "[redacted]"
+
+// EnvValidator is satisfied by zog schemas such as zog.String() or zog.IntLike[uint16]()
+type EnvValidator[T any] interface {
+	Validate(data *T, options ...zog.ExecOption) zog.ZogIssueList
+}
+
+// EnvVarError describes a single declared variable that failed validation
+type EnvVarError struct {
+	Err    error
+	Key    string
+	Secret bool
+}
+
+func (e *EnvVarError) Error() string {
+	var parseErr *EnvParseError
+
+	// The parse error of a secret may embed the value, only keep the expected type
+	if e.Secret && errors.As(e.Err, &parseErr) {
+		return "env " + e.Key + ": invalid " + parseErr.Type + " value"
+	}
+
+	return "env " + e.Key + ": " + e.Err.Error()
+}
+
+func (e *EnvVarError) Unwrap() error {
+	return e.Err
+}
+
+// EnvIssuesError carries the zog issues of a value rejected by a schema, it matches ErrEnvInvalid
+type EnvIssuesError struct {
+	Issues zog.ZogIssueList
+}
+
+func (e *EnvIssuesError) Error() string {
+	return ErrEnvInvalid.Error() + ": " + strings.Join(zog.Issues.SanitizeListAndCollect(e.Issues), ", ")
+}
+
+func (e *EnvIssuesError) Unwrap() error {
+	return ErrEnvInvalid
+}
+
+// EnvVar declares a variable read by a service, build it with EnvString, EnvInt, ... or EnvVarOf
+type EnvVar struct {
+	check       func(e Env) (bool, error)
+	key         string
+	typ         string
+	description string
+	defaultVal  string
+	required    bool
+	secret      bool
+}
+
+// EnvVarOf declares a variable parsed by lookup and validated by the optional zog schemas
+func EnvVarOf[T any](key, description string, lookup func(e Env, key string) (T, bool, error), schema ...EnvValidator[T]) EnvVar {
+	return EnvVar{
+		key:         key,
+		typ:         typeName[T](),
+		description: description,
+		check: func(e Env) (bool, error) {
+			value, exists, err := lookup(e, key)
+			if err != nil || !exists {
+				return exists, err
+			}
+
+			for _, s := range schema {
+				if err := zogError(s.Validate(&value)); err != nil {
+					return true, err
+				}
+			}
+
+			return true, nil
+		},
+	}
+}
+
+func EnvString(key, description string, schema ...EnvValidator[string]) EnvVar {
+	return EnvVarOf(key, description, func(e Env, key string) (string, bool, error) {
+		return e.Lookup(key)
+	}, schema...)
+}
+
+func EnvInt[T int | int8 | int16 | int32 | int64](key, description string, schema ...EnvValidator[T]) EnvVar {
+	return EnvVarOf(key, description, LookupIntEnv[T], schema...)
+}
+
+func EnvUint[T uint | uint8 | uint16 | uint32 | uint64](key, description string, schema ...EnvValidator[T]) EnvVar {
+	return EnvVarOf(key, description, LookupUintEnv[T], schema...)
+}
+
+func EnvFloat[T float32 | float64](key, description string, schema ...EnvValidator[T]) EnvVar {
+	return EnvVarOf(key, description, LookupFloatEnv[T], schema...)
+}
+
+func EnvBool(key, description string) EnvVar {
+	return EnvVarOf(key, description, LookupBoolEnv)
+}
+
+func EnvDuration(key, description string, schema ...EnvValidator[time.Duration]) EnvVar {
+	return EnvVarOf(key, description, LookupDurationEnv, schema...)
+}
+
+// EnvStrings declares a comma separated list, the schemas validate every element
+func EnvStrings(key, description string, schema ...EnvValidator[string]) EnvVar {
+	parse := listParser(func(value string) (string, error) {
+		for _, s := range schema {
+			if err := zogError(s.Validate(&value)); err != nil {
+				return "", err
+			}
+		}
+
+		return value, nil
+	})
+
+	return EnvVarOf(key, description, func(e Env, key string) ([]string, bool, error) {
+		value, exists, err := e.Lookup(key)
+		if err != nil || !exists {
+			return nil, exists, err
+		}
+
+		values, err := parse(value)
+
+		return values, true, err
+	})
+}
+
+// EnvKey declares a key decoded by ParseKey, keys are always secret
+func EnvKey(key, description string) EnvVar {
+	v := EnvVarOf(key, description, LookupKeyEnv)
+	v.secret = true
+
+	return v
+}
+
+// Default sets the value used when the variable is not set, it is validated like any other value
+func (v EnvVar) Default(value string) EnvVar {
+	v.defaultVal = value
+
+	return v
+}
+
+// Required marks the variable as mandatory when it has no default
+func (v EnvVar) Required() EnvVar {
+	v.required = true
+
+	return v
+}
+
+// Secret keeps the value out of errors and generated documentation
+func (v EnvVar) Secret() EnvVar {
+	v.secret = true
+
+	return v
+}
+
+func (v EnvVar) Key() string {
+	return v.key
+}
+
+// EnvSchema is the full set of variables read by a service
+//
+//	schema := utils.NewEnvSchema(
+//		utils.EnvUint[uint16]("PORT", "HTTP listen port", zog.UintLike[uint16]().GT(0)).Default("8080"),
+//		utils.EnvString("DATABASE_URL", "Postgres connection string").Required().Secret(),
+//		utils.EnvKey("APP_KEY", "URL signing key").Required(),
+//	)
+//
+//	if err := schema.Validate(env); err != nil {
+//		log.Fatal(err) // lists every problem
+//	}
+type EnvSchema struct {
+	vars []EnvVar
+}
+
+func NewEnvSchema(vars ...EnvVar) *EnvSchema {
+	return &EnvSchema{vars: vars}
+}
+
+// Vars returns the declared variables in declaration order
+func (s *EnvSchema) Vars() []EnvVar {
+	return s.vars
+}
+
+// Validate checks every declared variable against e, defaults included.
+// The returned error joins an *EnvVarError for each problem.
+func (s *EnvSchema) Validate(e Env) error {
+	var errs []error
+
+	for _, v := range s.vars {
+		env := e
+		exists, err := v.check(env)
+
+		if err == nil && !exists {
+			switch {
+			case v.defaultVal != "":
+				env = Env{EnvProvider: NewMapEnvProvider(map[string]string{v.key: v.defaultVal})}
+
+				_, err = v.check(env)
+				if err != nil {
+					err = fmt.Errorf("default: %w", err)
+				}
+			case v.required:
+				err = ErrMissingEnv
+			}
+		}
+
+		if err != nil && v.secret {
+			raw, _, _ := env.Lookup(v.key)
+			redactEnvError(err, raw)
+		}
+
+		if err != nil {
+			errs = append(errs, &EnvVarError{Key: v.key, Secret: v.secret, Err: err})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MustValidate is like Validate but panics listing every problem
+func (s *EnvSchema) MustValidate(e Env) {
+	if err := s.Validate(e); err != nil {
+		panic(err)
+	}
+}
+
+// WriteExample writes a .env.example file. Required variables are left empty for the operator to fill in,
+// optional ones are commented out with their default. Secret defaults are never written.
+func (s *EnvSchema) WriteExample(w io.Writer) error {
+	var b strings.Builder
+
+	for i, v := range s.vars {
+		if i > 0 {
+			_ = b.WriteByte('\n')
+		}
+
+		if v.description != "" {
+			_, _ = b.WriteString("# " + v.description + "\n")
+		}
+
+		_, _ = b.WriteString("# " + v.typ + ", " + v.flags() + "\n")
+
+		value := v.defaultVal
+		if v.secret {
+			value = ""
+		}
+
+		if !v.required || v.defaultVal != "" {
+			_, _ = b.WriteString("# ")
+		}
+
+		_, _ = b.WriteString(v.key + "=" + quoteDotEnv(value) + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// WriteMarkdown writes a markdown table documenting every declared variable
+func (s *EnvSchema) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	_, _ = b.WriteString("| Variable | Type | Required | Default | Description |\n")
+	_, _ = b.WriteString("| --- | --- | --- | --- | --- |\n")
+
+	for _, v := range s.vars {
+		def := ""
+
+		switch {
+		case v.secret:
+			def = "*secret*"
+		case v.defaultVal != "":
+			def = "`" + v.defaultVal + "`"
+		}
+
+		required := "no"
+		if v.required && v.defaultVal == "" {
+			required = "yes"
+		}
+
+		_, _ = b.WriteString("| `" + v.key + "` | " + v.typ + " | " + required + " | " +
+			escapeMarkdownCell(def) + " | " + escapeMarkdownCell(v.description) + " |\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func (v EnvVar) flags() string {
+	flags := "optional"
+	if v.required && v.defaultVal == "" {
+		flags = "required"
+	}
+
+	if v.secret {
+		flags += ", secret"
+	}
+
+	return flags
+}
+
+func zogError(issues zog.ZogIssueList) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	return &EnvIssuesError{Issues: issues}
+}
+
+// redactEnvError strips raw, the value of a secret, from the parse error and zog issues carried by err
+func redactEnvError(err error, raw string) {
+	var parseErr *EnvParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Value = ""
+		parseErr.Err = redactedCause(parseErr.Err, raw)
+	}
+
+	var issuesErr *EnvIssuesError
+	if errors.As(err, &issuesErr) {
+		for _, issue := range issuesErr.Issues {
+			issue.Value = nil
+			issue.Message = redactValue(issue.Message, raw)
+		}
+	}
+}
+
+// redactedCause returns the innermost cause of err, parsers such as strconv keep the input out of it
+func redactedCause(err error, raw string) error {
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(err) {
+		err = inner
+	}
+
+	if redactValue(err.Error(), raw) != err.Error() {
+		return errEnvRedacted
+	}
+
+	return err
+}
+
+// redactValue replaces raw and each of its list elements in message
+func redactValue(message, raw string) string {
+	if raw == "" {
+		return message
+	}
+
+	message = strings.ReplaceAll(message, raw, redacted)
+	for _, v := range parseStrings(raw) {
+		message = strings.ReplaceAll(message, v, redacted)
+	}
+
+	return message
+}
+
+// quoteDotEnv single quotes values godotenv would otherwise split, expand or strip
+func quoteDotEnv(value string) string {
+	if !strings.ContainsAny(value, " \t#$\"'\\") {
+		return value
+	}
+
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+
+	return strconv.Quote(value)
+}
+
+func escapeMarkdownCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "|", `\|`), "\n", " ")
+}
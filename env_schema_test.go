@@ -0,0 +1,189 @@
+package utils_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Oudwins/zog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func mapEnv(values map[string]string) utils.Env {
+	return utils.Env{EnvProvider: utils.NewMapEnvProvider(values)}
+}
+
+func newTestSchema() *utils.EnvSchema {
+	return utils.NewEnvSchema(
+		utils.EnvUint[uint16]("PORT", "HTTP listen port", zog.UintLike[uint16]().GT(1023)).Default("8080"),
+		utils.EnvString("DATABASE_URL", "Postgres connection string", zog.String().URL()).Required().Secret(),
+		utils.EnvKey("APP_KEY", "URL signing key").Required(),
+		utils.EnvDuration("TIMEOUT", "Request timeout | upstream", zog.IntLike[time.Duration]().LTE(time.Minute)).Default("30s"),
+		utils.EnvStrings("ALLOWED_HOSTS", "Hosts accepted by the router", zog.String().Min(3)),
+		utils.EnvBool("DEBUG", "").Default("false"),
+		utils.EnvString("GREETING", "Banner").Default("hello world"),
+	)
+}
+
+func TestEnvSchema_Validate(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	schema := newTestSchema()
+
+	env := mapEnv(map[string]string{
+		"DATABASE_URL":  "postgres://app@localhost/app",
+		"APP_KEY":       "base64:dGVzdGluZ2tleWRhdGF0ZXN0aW5na2V5ZGF0YQ==",
+		"ALLOWED_HOSTS": "example.com, api.example.com",
+	})
+	req.NoError(schema.Validate(env))
+	req.NotPanics(func() { schema.MustValidate(env) })
+}
+
+func TestEnvSchema_ValidateListsEveryProblem(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	schema := newTestSchema()
+
+	err := schema.Validate(mapEnv(map[string]string{
+		"PORT":          "80",
+		"DATABASE_URL":  "not a url",
+		"TIMEOUT":       "soon",
+		"ALLOWED_HOSTS": "example.com,a",
+	}))
+	req.Error(err)
+
+	var issues []*utils.EnvVarError
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	req.True(ok)
+
+	for _, e := range joined.Unwrap() {
+		var varErr *utils.EnvVarError
+		req.ErrorAs(e, &varErr)
+		issues = append(issues, varErr)
+	}
+
+	keys := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+
+	req.Equal([]string{"PORT", "DATABASE_URL", "APP_KEY", "TIMEOUT", "ALLOWED_HOSTS"}, keys)
+	req.ErrorIs(issues[0], utils.ErrEnvInvalid)
+	req.ErrorIs(issues[1], utils.ErrEnvInvalid)
+	req.ErrorIs(issues[2], utils.ErrMissingEnv)
+
+	var parseErr *utils.EnvParseError
+	req.ErrorAs(issues[3], &parseErr)
+	req.Equal("duration", parseErr.Type)
+	req.ErrorContains(issues[4], "element 1")
+
+	req.Panics(func() { schema.MustValidate(mapEnv(nil)) })
+}
+
+func TestEnvSchema_ValidateDefaultsAndSecrets(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	schema := utils.NewEnvSchema(
+		utils.EnvInt[int]("WORKERS", "").Default("many"),
+		utils.EnvInt[int]("PIN", "").Secret(),
+	)
+
+	err := schema.Validate(mapEnv(map[string]string{"PIN": "12a4"}))
+	req.Error(err)
+	req.ErrorContains(err, "env WORKERS: default: invalid int value")
+	req.ErrorContains(err, "env PIN: invalid int value")
+	req.NotContains(err.Error(), "12a4")
+
+	var parseErr *utils.EnvParseError
+	req.True(errors.As(err, &parseErr))
+}
+
+func TestEnvSchema_ValidateRedactsSecretIssues(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	schema := utils.NewEnvSchema(
+		utils.EnvString("TOKEN", "", zog.String().Min(16, zog.MessageFunc(func(e *zog.ZogIssue, _ zog.Ctx) {
+			e.SetMessage(*e.Value.(*string) + " is too short")
+		}))).Secret(),
+		utils.EnvInt[int]("PIN", "").Secret(),
+	)
+
+	err := schema.Validate(mapEnv(map[string]string{"TOKEN": "hunter2", "PIN": "12a4"}))
+	req.Error(err)
+	req.ErrorContains(err, "env TOKEN: validation failed: [redacted] is too short")
+	req.NotContains(err.Error(), "hunter2")
+
+	var issuesErr *utils.EnvIssuesError
+	req.ErrorAs(err, &issuesErr)
+	req.ErrorIs(err, utils.ErrEnvInvalid)
+	req.Len(issuesErr.Issues, 1)
+	req.Nil(issuesErr.Issues[0].Value)
+	req.NotContains(issuesErr.Issues[0].Message, "hunter2")
+
+	var parseErr *utils.EnvParseError
+	req.ErrorAs(err, &parseErr)
+	req.Empty(parseErr.Value)
+	req.NotContains(parseErr.Error(), "12a4")
+	req.ErrorIs(err, strconv.ErrSyntax)
+}
+
+func TestEnvSchema_WriteExample(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	require.NoError(t, newTestSchema().WriteExample(&b))
+
+	require.Equal(t, `# HTTP listen port
+# uint16, optional
+# PORT=8080
+
+# Postgres connection string
+# string, required, secret
+DATABASE_URL=
+
+# URL signing key
+# key, required, secret
+APP_KEY=
+
+# Request timeout | upstream
+# duration, optional
+# TIMEOUT=30s
+
+# Hosts accepted by the router
+# list, optional
+# ALLOWED_HOSTS=
+
+# bool, optional
+# DEBUG=false
+
+# Banner
+# string, optional
+# GREETING='hello world'
+`, b.String())
+}
+
+func TestEnvSchema_WriteMarkdown(t *testing.T) {
+	t.Parallel()
+
+	var b strings.Builder
+	require.NoError(t, newTestSchema().WriteMarkdown(&b))
+
+	require.Equal(t, "| Variable | Type | Required | Default | Description |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `PORT` | uint16 | no | `8080` | HTTP listen port |\n"+
+		"| `DATABASE_URL` | string | yes | *secret* | Postgres connection string |\n"+
+		"| `APP_KEY` | key | yes | *secret* | URL signing key |\n"+
+		"| `TIMEOUT` | duration | no | `30s` | Request timeout \\| upstream |\n"+
+		"| `ALLOWED_HOSTS` | list | no |  | Hosts accepted by the router |\n"+
+		"| `DEBUG` | bool | no | `false` |  |\n"+
+		"| `GREETING` | string | no | `hello world` | Banner |\n", b.String())
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+)
+
+const (
+	SecretFileSuffix         = "_FILE"
+	DefaultSecretFileMaxSize = 64 * KiB
+	DefaultSecretFileMaxPerm = fs.FileMode(0o644)
+)
+
+var (
+	ErrSecretFileTooLarge   = errors.New("secret file exceeds size limit")
+	ErrSecretFilePermission = errors.New("secret file permissions are too open")
+	ErrSecretFileNotRegular = errors.New("secret file is not a regular file")
+)
+
+// SecretFileError is returned when the file referenced by KEY_FILE cannot be used
+type SecretFileError struct {
+	Err  error
+	Key  string
+	Path string
+}
+
+func (e *SecretFileError) Error() string {
+	return "secret file for " + e.Key + " (" + e.Path + "): " + e.Err.Error()
+}
+
+func (e *SecretFileError) Unwrap() error {
+	return e.Err
+}
+
+// SecretFileOptions configures SecretFileEnvProvider, zero values fall back to the defaults
+type SecretFileOptions struct {
+	// Suffix appended to the key to find the file path, defaults to SecretFileSuffix
+	Suffix string
+	// MaxSize is the largest accepted file, defaults to DefaultSecretFileMaxSize
+	MaxSize MemorySize
+	// MaxPerm is the most permissive mode accepted, defaults to DefaultSecretFileMaxPerm.
+	// The check is skipped on Windows where unix permission bits are not meaningful.
+	MaxPerm fs.FileMode
+}
+
+// SecretFileEnvProvider resolves KEY from the file named by KEY_FILE when KEY itself is not set,
+// following the Docker and Kubernetes secrets convention
+//
+//	DB_PASSWORD_FILE=/run/secrets/db_password
+type SecretFileEnvProvider struct {
+	inner EnvProvider
+	opts  SecretFileOptions
+}
+
+func NewSecretFileEnvProvider(inner EnvProvider, opts ...SecretFileOptions) *SecretFileEnvProvider {
+	return &SecretFileEnvProvider{
+		inner: inner,
+		opts:  secretFileOptions(opts),
+	}
+}
+
+// ReadSecretFile reads a secret from path with the checks of SecretFileEnvProvider: the file must be regular,
+// at most MaxSize long and not more permissive than MaxPerm. Surrounding whitespace is trimmed, Suffix is ignored.
+func ReadSecretFile(path string, opts ...SecretFileOptions) (string, error) {
+	return readSecretFile(path, secretFileOptions(opts))
+}
+
+func secretFileOptions(opts []SecretFileOptions) SecretFileOptions {
+	var o SecretFileOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.Suffix == "" {
+		o.Suffix = SecretFileSuffix
+	}
+
+	if o.MaxSize == 0 {
+		o.MaxSize = DefaultSecretFileMaxSize
+	}
+
+	if o.MaxPerm == 0 {
+		o.MaxPerm = DefaultSecretFileMaxPerm
+	}
+
+	return o
+}
+
+// Get panics if KEY_FILE is set but the file cannot be used, use Lookup to handle the error
+func (p *SecretFileEnvProvider) Get(key string) (string, bool) {
+	value, exists, err := p.Lookup(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return value, exists
+}
+
+func (p *SecretFileEnvProvider) Set(key, value string) {
+	p.inner.Set(key, value)
+}
+
+func (p *SecretFileEnvProvider) Lookup(key string) (string, bool, error) {
+	if value, exists := p.inner.Get(key); exists {
+		return value, true, nil
+	}
+
+	path, exists := p.inner.Get(key + p.opts.Suffix)
+	if !exists || path == "" {
+		return "", false, nil
+	}
+
+	value, err := readSecretFile(path, p.opts)
+	if err != nil {
+		return "", true, &SecretFileError{Key: key, Path: path, Err: err}
+	}
+
+	return value, true, nil
+}
+
+func readSecretFile(path string, opts SecretFileOptions) (string, error) {
+	//#nosec G304
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", ErrSecretFileNotRegular
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&^opts.MaxPerm != 0 {
+		return "", fmt.Errorf("%w: mode %#o", ErrSecretFilePermission, info.Mode().Perm())
+	}
+
+	if info.Size() > int64(opts.MaxSize) { //nolint:gosec
+		return "", ErrSecretFileTooLarge
+	}
+
+	// The file may grow between Stat and Read
+	data, err := io.ReadAll(io.LimitReader(file, int64(opts.MaxSize)+1)) //nolint:gosec
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) > int(opts.MaxSize) { //nolint:gosec
+		return "", ErrSecretFileTooLarge
+	}
+
+	return strings.TrimSpace(UnsafeString(data)), nil
+}
@@ -0,0 +1,125 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func writeSecret(tb testing.TB, dir, name, content string, perm os.FileMode) string {
+	tb.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		tb.Fatal(err)
+	}
+
+	// WriteFile is subject to umask
+	if err := os.Chmod(path, perm); err != nil {
+		tb.Fatal(err)
+	}
+
+	return path
+}
+
+func TestSecretFileEnvProvider(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	inner := utils.NewMapEnvProvider(map[string]string{
+		"DB_PASSWORD_FILE": writeSecret(t, dir, "db", "s3cr3t\n", 0o400),
+		"DB_USER":          "app",
+		"DB_USER_FILE":     writeSecret(t, dir, "user", "ignored", 0o400),
+		"APP_KEY_FILE":     writeSecret(t, dir, "key", " base64:dGVzdGtleQ== \n", 0o444),
+	})
+	p := utils.NewSecretFileEnvProvider(inner)
+	env := utils.Env{EnvProvider: p}
+
+	req.Equal("s3cr3t", utils.GetEnv(env, "DB_PASSWORD", ""))
+	// the plain variable takes precedence over the file
+	req.Equal("app", utils.GetEnv(env, "DB_USER", ""))
+	req.Equal([]byte("testkey"), utils.GetKeyEnv(env, "APP_KEY", nil))
+
+	_, ok := p.Get("MISSING")
+	req.False(ok)
+
+	p.Set("DB_PASSWORD", "override")
+	req.Equal("override", utils.GetEnv(env, "DB_PASSWORD", ""))
+}
+
+func TestSecretFileEnvProvider_Errors(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	inner := utils.NewMapEnvProvider(map[string]string{
+		"LARGE_FILE":   writeSecret(t, dir, "large", strings.Repeat("a", 32), 0o400),
+		"OPEN_FILE":    writeSecret(t, dir, "open", "secret", 0o666),
+		"DIR_FILE":     dir,
+		"MISSING_FILE": filepath.Join(dir, "does-not-exist"),
+	})
+	p := utils.NewSecretFileEnvProvider(inner, utils.SecretFileOptions{MaxSize: 16})
+	env := utils.Env{EnvProvider: p}
+
+	var fileErr *utils.SecretFileError
+
+	_, exists, err := env.Lookup("LARGE")
+	req.True(exists)
+	req.ErrorIs(err, utils.ErrSecretFileTooLarge)
+	req.ErrorAs(err, &fileErr)
+	req.Equal("LARGE", fileErr.Key)
+
+	if runtime.GOOS != "windows" {
+		_, _, err = env.Lookup("OPEN")
+		req.ErrorIs(err, utils.ErrSecretFilePermission)
+	}
+
+	_, _, err = env.Lookup("DIR")
+	req.ErrorIs(err, utils.ErrSecretFileNotRegular)
+
+	_, _, err = env.Lookup("MISSING")
+	req.ErrorIs(err, os.ErrNotExist)
+
+	// Lookup*Env surface the error, Get*Env panic with it
+	_, _, err = utils.LookupKeyEnv(env, "LARGE")
+	req.ErrorIs(err, utils.ErrSecretFileTooLarge)
+	req.Panics(func() { _ = utils.GetKeyEnv(env, "LARGE", nil) })
+	req.Panics(func() { _, _ = p.Get("LARGE") })
+
+	var cfg struct {
+		Key []byte `env:"LARGE"`
+	}
+	req.ErrorIs(utils.Bind(env, &cfg), utils.ErrSecretFileTooLarge)
+}
+
+func TestSecretFileEnvProvider_InLayers(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	secrets := utils.NewSecretFileEnvProvider(utils.NewMapEnvProvider(map[string]string{
+		"TOKEN_FILE": writeSecret(t, dir, "token", "from-file", 0o400),
+		"BAD_FILE":   filepath.Join(dir, "missing"),
+	}))
+	layered := utils.NewLayeredEnvProvider(
+		utils.EnvLayer{Name: "secrets", Provider: secrets},
+		utils.EnvLayer{Name: "defaults", Provider: utils.NewMapEnvProvider(map[string]string{"TOKEN": "default", "BAD": "x"})},
+	)
+	env := utils.Env{EnvProvider: layered}
+
+	req.Equal("from-file", utils.GetEnv(env, "TOKEN", ""))
+
+	source, ok := layered.Source("TOKEN")
+	req.True(ok)
+	req.Equal("secrets", source)
+
+	_, _, err := env.Lookup("BAD")
+	req.ErrorIs(err, os.ErrNotExist)
+}
@@ -441,6 +441,19 @@ func TestGetKeyEnv(t *testing.T) {
 	require.NotEqual(t, defaultKey, result)
 	require.NotEmpty(t, result)
 
+	// Every ParseKey format is accepted
+	for value, expected := range map[string][]byte{
+		"hex:0102":         {1, 2},
+		"base64url:AQL_":   {1, 2, 0xff},
+		"base32:AEBA":      {1, 2},
+		"raw:passphrase":   []byte("passphrase"),
+		"base64:AQI=":      {1, 2},
+		"0102030405060708": {1, 2, 3, 4, 5, 6, 7, 8},
+	} {
+		p.Set("CRYPTO_KEY_FORMAT", value)
+		require.Equal(t, expected, utils.GetKeyEnv(p, "CRYPTO_KEY_FORMAT", defaultKey), value)
+	}
+
 	// Test with empty key returns default
 	p.Set("EMPTY_KEY", "")
 	result = utils.GetKeyEnv(p, "EMPTY_KEY", defaultKey)
@@ -470,15 +483,8 @@ func TestNewEnv(t *testing.T) {
 	env := utils.NewEnv(true)
 	require.NotNil(t, env.EnvProvider)
 
-	// Test NewEnv with dotenv enabled (will try to load .env file)
-	// This might panic if .env file doesn't exist, which is expected behavior
-	defer func() {
-		if r := recover(); r != nil {
-			// Expected panic if .env file doesn't exist
-			require.Contains(t, r.(string), "dotenv file not found")
-		}
-	}()
-	env = utils.NewEnv(false)
+	// Test NewEnv with dotenv enabled, missing and malformed dotenv files are skipped
+	require.NotPanics(t, func() { env = utils.NewEnv(false) })
 	require.NotNil(t, env.EnvProvider)
 }
 
@@ -500,7 +506,7 @@ func TestLoadDotEnv_LoadError(t *testing.T) {
 
 	// This should not panic but should log an error and return
 	require.NotPanics(t, func() {
-		utils.MustLoadEnv(tmpDir)
+		utils.LoadEnv(tmpDir)
 	})
 }
 
@@ -555,3 +561,137 @@ func TestOsEnvProvider_SetError(t *testing.T) {
 		provider.Set(invalidKey, "test_value")
 	})
 }
+
+func TestLookupEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	p := utils.NewTestEnv(t, "LOOKUP_")
+
+	p.Set("INT", "42")
+	p.Set("UINT", "7")
+	p.Set("FLOAT", "2.5")
+	p.Set("BOOL", "true")
+	p.Set("DURATION", "90")
+	p.Set("KEY", "base64:dGVzdGtleQ==")
+	p.Set("EMPTY_KEY", "")
+
+	i, ok, err := utils.LookupIntEnv[int16](p, "INT")
+	req.NoError(err)
+	req.True(ok)
+	req.Equal(int16(42), i)
+
+	u, ok, err := utils.LookupUintEnv[uint8](p, "UINT")
+	req.NoError(err)
+	req.True(ok)
+	req.Equal(uint8(7), u)
+
+	f, ok, err := utils.LookupFloatEnv[float32](p, "FLOAT")
+	req.NoError(err)
+	req.True(ok)
+	req.InEpsilon(float32(2.5), f, 0.0001)
+
+	b, ok, err := utils.LookupBoolEnv(p, "BOOL")
+	req.NoError(err)
+	req.True(ok)
+	req.True(b)
+
+	p.Set("LIST", "a, b,,c")
+
+	list, ok, err := utils.LookupStringsEnv(p, "LIST")
+	req.NoError(err)
+	req.True(ok)
+	req.Equal([]string{"a", "b", "c"}, list)
+
+	list, ok, err = utils.LookupStringsEnv(p, "LIST_MISSING")
+	req.NoError(err)
+	req.False(ok)
+	req.Nil(list)
+
+	d, ok, err := utils.LookupDurationEnv(p, "DURATION")
+	req.NoError(err)
+	req.True(ok)
+	req.Equal(90*time.Second, d)
+
+	k, ok, err := utils.LookupKeyEnv(p, "KEY")
+	req.NoError(err)
+	req.True(ok)
+	req.Equal([]byte("testkey"), k)
+
+	k, ok, err = utils.LookupKeyEnv(p, "EMPTY_KEY")
+	req.NoError(err)
+	req.False(ok)
+	req.Nil(k)
+
+	i, ok, err = utils.LookupIntEnv[int16](p, "MISSING")
+	req.NoError(err)
+	req.False(ok)
+	req.Zero(i)
+}
+
+func TestLookupEnv_ParseError(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	p := utils.NewTestEnv(t)
+
+	p.Set("LOOKUP_INT8_OVER", "128")
+	_, ok, err := utils.LookupIntEnv[int8](p, "LOOKUP_INT8_OVER")
+	req.True(ok)
+
+	var parseErr *utils.EnvParseError
+	req.ErrorAs(err, &parseErr)
+	req.Equal("LOOKUP_INT8_OVER", parseErr.Key)
+	req.Equal("128", parseErr.Value)
+	req.Equal("int8", parseErr.Type)
+	req.ErrorIs(err, strconv.ErrRange)
+	req.Equal(`invalid int8 value for LOOKUP_INT8_OVER: strconv.ParseInt: value out of range`, err.Error())
+
+	// short values are left out whole, not replaced wherever they happen to occur
+	p.Set("LOOKUP_BOOL_SHORT", "y")
+	_, _, err = utils.LookupBoolEnv(p, "LOOKUP_BOOL_SHORT")
+	req.Equal(`invalid bool value for LOOKUP_BOOL_SHORT: strconv.ParseBool: invalid syntax`, err.Error())
+
+	p.Set("LOOKUP_INT_SHORT", "a,s")
+	_, _, err = utils.LookupIntEnv[int](p, "LOOKUP_INT_SHORT")
+	req.Equal(`invalid int value for LOOKUP_INT_SHORT: strconv.ParseInt: invalid syntax`, err.Error())
+
+	p.Set("LOOKUP_BAD_KEY", "not-hex-secret")
+	_, ok, err = utils.LookupKeyEnv(p, "LOOKUP_BAD_KEY")
+	req.True(ok)
+	req.ErrorAs(err, &parseErr)
+	req.Equal(utils.EnvTypeKey, parseErr.Type)
+	req.NotContains(err.Error(), "not-hex-secret")
+
+	p.Set("LOOKUP_BAD_DURATION", "soon")
+	_, _, err = utils.LookupDurationEnv(p, "LOOKUP_BAD_DURATION")
+	req.ErrorAs(err, &parseErr)
+	req.Equal("duration", parseErr.Type)
+
+	// Get*Env panics with the same typed error
+	req.PanicsWithError(err.Error(), func() { _ = utils.GetDurationEnv(p, "LOOKUP_BAD_DURATION", time.Second) })
+}
+
+func TestGetKeysEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	env := utils.Env{EnvProvider: utils.NewMapEnvProvider(map[string]string{
+		"KEYS":   "base64:AQI=, 0304,",
+		"EMPTY":  "",
+		"BROKEN": "0102,base64:***",
+	})}
+
+	req.Equal([][]byte{{1, 2}, {3, 4}}, utils.GetKeysEnv(env, "KEYS", nil))
+	req.Equal([][]byte{{9}}, utils.GetKeysEnv(env, "EMPTY", [][]byte{{9}}))
+
+	_, ok, err := utils.LookupKeysEnv(env, "MISSING")
+	req.False(ok)
+	req.NoError(err)
+
+	_, ok, err = utils.LookupKeysEnv(env, "BROKEN")
+	req.True(ok)
+
+	var parseErr *utils.EnvParseError
+	req.ErrorAs(err, &parseErr)
+	req.Equal("key list", parseErr.Type)
+	req.NotContains(err.Error(), "0102")
+}
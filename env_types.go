@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRelativeURL   = errors.New("URL must be absolute")
+	ErrNotAllowed    = errors.New("value is not allowed")
+	ErrMalformedPair = errors.New("expected key=value")
+	ErrUnknownZone   = errors.New("unknown time zone")
+)
+
+// GetEnvFunc Gets a value parsed by parse from environment variable or returns a default value.
+// It panics with an *EnvParseError if parse fails.
+//
+//	origin := utils.GetEnvFunc(env, "CORS_ORIGIN", parseOrigin, defaultOrigin)
+func GetEnvFunc[T any](e Env, key string, parse func(string) (T, error), defaultValue T) T {
+	value, exists, err := LookupEnvFunc(e, key, parse)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupEnvFunc Gets a value parsed by parse from environment variable, reports whether it was set
+// and returns an *EnvParseError if parse fails
+func LookupEnvFunc[T any](e Env, key string, parse func(string) (T, error)) (T, bool, error) {
+	return lookupEnv(e, key, typeName[T](), parse)
+}
+
+// GetURLEnv Gets an absolute URL from environment variable or returns a default value
+func GetURLEnv(e Env, key string, defaultValue *url.URL) *url.URL {
+	value, exists, err := LookupURLEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupURLEnv Gets an absolute URL from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed or has no scheme
+func LookupURLEnv(e Env, key string) (*url.URL, bool, error) {
+	return lookupEnv(e, key, typeName[*url.URL](), parseURL)
+}
+
+// GetAddrEnv Gets an IP address from environment variable or returns a default value
+func GetAddrEnv(e Env, key string, defaultValue netip.Addr) netip.Addr {
+	value, exists, err := LookupAddrEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupAddrEnv Gets an IP address from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed
+func LookupAddrEnv(e Env, key string) (netip.Addr, bool, error) {
+	return lookupEnv(e, key, typeName[netip.Addr](), netip.ParseAddr)
+}
+
+// GetAddrsEnv Gets a comma separated list of IP addresses from environment variable or returns a default value
+func GetAddrsEnv(e Env, key string, defaultValue []netip.Addr) []netip.Addr {
+	value, exists, err := LookupAddrsEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupAddrsEnv Gets a comma separated list of IP addresses from environment variable,
+// reports whether it was set and returns an *EnvParseError if any element is malformed
+func LookupAddrsEnv(e Env, key string) ([]netip.Addr, bool, error) {
+	return lookupEnv(e, key, typeName[[]netip.Addr](), listParser(netip.ParseAddr))
+}
+
+// GetPrefixesEnv Gets a comma separated list of CIDR ranges from environment variable or returns a default value.
+// Bare addresses are accepted as single host ranges.
+//
+//	TRUSTED_PROXIES=10.0.0.0/8,fd00::/8,127.0.0.1
+func GetPrefixesEnv(e Env, key string, defaultValue []netip.Prefix) []netip.Prefix {
+	value, exists, err := LookupPrefixesEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupPrefixesEnv Gets a comma separated list of CIDR ranges from environment variable,
+// reports whether it was set and returns an *EnvParseError if any element is malformed
+func LookupPrefixesEnv(e Env, key string) ([]netip.Prefix, bool, error) {
+	return lookupEnv(e, key, typeName[[]netip.Prefix](), listParser(parsePrefix))
+}
+
+// GetEnumEnv Gets one of allowed from environment variable or returns a default value
+//
+//	mode := utils.GetEnumEnv(env, "CACHE_MODE", []CacheMode{CacheOff, CacheMemory, CacheRedis}, CacheMemory)
+func GetEnumEnv[T ~string](e Env, key string, allowed []T, defaultValue T) T {
+	value, exists, err := LookupEnumEnv(e, key, allowed)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupEnumEnv Gets one of allowed from environment variable, reports whether it was set
+// and returns an *EnvParseError wrapping ErrNotAllowed for any other value
+func LookupEnumEnv[T ~string](e Env, key string, allowed []T) (T, bool, error) {
+	return lookupEnv(e, key, "enum", func(value string) (T, error) {
+		if slices.Contains(allowed, T(value)) {
+			return T(value), nil
+		}
+
+		names := make([]string, len(allowed))
+		for i, a := range allowed {
+			names[i] = string(a)
+		}
+
+		return "", fmt.Errorf("%w, expected one of %s", ErrNotAllowed, strings.Join(names, ", "))
+	})
+}
+
+// GetMapEnv Gets a k1=v1,k2=v2 map from environment variable or returns a default value.
+// Keys and values are trimmed, values may contain '='.
+func GetMapEnv(e Env, key string, defaultValue map[string]string) map[string]string {
+	value, exists, err := LookupMapEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupMapEnv Gets a k1=v1,k2=v2 map from environment variable, reports whether it was set
+// and returns an *EnvParseError wrapping ErrMalformedPair if an element is not a key=value pair
+func LookupMapEnv(e Env, key string) (map[string]string, bool, error) {
+	return lookupEnv(e, key, typeName[map[string]string](), parseMap)
+}
+
+// GetTimeEnv Gets a time in layout (time.RFC3339 by default) from environment variable or returns a default value
+func GetTimeEnv(e Env, key string, defaultValue time.Time, layout ...string) time.Time {
+	value, exists, err := LookupTimeEnv(e, key, layout...)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupTimeEnv Gets a time in layout (time.RFC3339 by default) from environment variable,
+// reports whether it was set and returns an *EnvParseError if the value is malformed
+func LookupTimeEnv(e Env, key string, layout ...string) (time.Time, bool, error) {
+	l := time.RFC3339
+	if len(layout) > 0 {
+		l = layout[0]
+	}
+
+	return lookupEnv(e, key, typeName[time.Time](), func(value string) (time.Time, error) {
+		return time.Parse(l, value)
+	})
+}
+
+// GetLocationEnv Gets an IANA time zone such as Europe/Belgrade from environment variable or returns a default value
+func GetLocationEnv(e Env, key string, defaultValue *time.Location) *time.Location {
+	value, exists, err := LookupLocationEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupLocationEnv Gets an IANA time zone from environment variable, reports whether it was set
+// and returns an *EnvParseError if the zone is unknown
+func LookupLocationEnv(e Env, key string) (*time.Location, bool, error) {
+	return lookupEnv(e, key, typeName[*time.Location](), parseLocation)
+}
+
+// GetLogLevelEnv Gets a slog level such as debug, INFO or warn+2 from environment variable or returns a default value
+func GetLogLevelEnv(e Env, key string, defaultValue slog.Level) slog.Level {
+	value, exists, err := LookupLogLevelEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupLogLevelEnv Gets a slog level from environment variable, reports whether it was set
+// and returns an *EnvParseError if the value is malformed
+func LookupLogLevelEnv(e Env, key string) (slog.Level, bool, error) {
+	return lookupEnv(e, key, typeName[slog.Level](), func(value string) (slog.Level, error) {
+		var level slog.Level
+		err := level.UnmarshalText([]byte(value))
+
+		return level, err
+	})
+}
+
+// GetMemorySizeEnv Gets a size parsed by ParseMemorySize, such as 512MiB, from environment variable
+// or returns a default value
+func GetMemorySizeEnv(e Env, key string, defaultValue MemorySize) MemorySize {
+	value, exists, err := LookupMemorySizeEnv(e, key)
+
+	return mustEnv(value, exists, err, defaultValue)
+}
+
+// LookupMemorySizeEnv Gets a size parsed by ParseMemorySize from environment variable,
+// reports whether it was set and returns an *EnvParseError if the value is malformed
+func LookupMemorySizeEnv(e Env, key string) (MemorySize, bool, error) {
+	return lookupEnv(e, key, typeName[MemorySize](), ParseMemorySize)
+}
+
+func parseURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsAbs() {
+		return nil, ErrRelativeURL
+	}
+
+	return u, nil
+}
+
+// parseLocation keeps the zone name out of the error, time.LoadLocation includes it
+func parseLocation(value string) (*time.Location, error) {
+	loc, err := time.LoadLocation(value)
+	if err != nil && strings.HasPrefix(err.Error(), ErrUnknownZone.Error()) {
+		return nil, ErrUnknownZone
+	}
+
+	return loc, err
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	return netip.ParsePrefix(value)
+}
+
+func parseMap(value string) (map[string]string, error) {
+	m := make(map[string]string)
+
+	for _, pair := range parseStrings(value) {
+		k, v, ok := strings.Cut(pair, "=")
+
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%w: %q", ErrMalformedPair, pair)
+		}
+
+		m[k] = strings.TrimSpace(v)
+	}
+
+	return m, nil
+}
+
+// listParser adapts an element parser to the comma separated lists accepted by GetStringsEnv
+func listParser[T any](parse func(string) (T, error)) func(string) ([]T, error) {
+	return func(value string) ([]T, error) {
+		values := parseStrings(value)
+		parsed := make([]T, len(values))
+
+		for i, v := range values {
+			var err error
+			if parsed[i], err = parse(v); err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+
+		return parsed, nil
+	}
+}
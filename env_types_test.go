@@ -0,0 +1,149 @@
+package utils_test
+
+import (
+	"log/slog"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+type cacheMode string
+
+func TestTypedEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	env := mapEnv(map[string]string{
+		"URL":      "https://example.com:8443/api?x=1",
+		"ADDR":     "::1",
+		"ADDRS":    "127.0.0.1, 10.0.0.1",
+		"PREFIXES": "10.0.0.0/8,fd00::/8, 192.168.1.10",
+		"MODE":     "redis",
+		"LABELS":   "team=core, tier = web,query=a=b",
+		"START":    "2025-01-02T03:04:05Z",
+		"DAY":      "2025-01-02",
+		"TZ":       "Europe/Belgrade",
+		"LEVEL":    "warn+2",
+		"CACHE":    "512MiB",
+		"UPPER":    "shout",
+	})
+
+	u := utils.GetURLEnv(env, "URL", nil)
+	req.Equal("example.com:8443", u.Host)
+	req.Equal("/api", u.Path)
+
+	fallback := &url.URL{Scheme: "http", Host: "localhost"}
+	req.Same(fallback, utils.GetURLEnv(env, "MISSING", fallback))
+
+	req.Equal(netip.IPv6Loopback(), utils.GetAddrEnv(env, "ADDR", netip.Addr{}))
+	req.Equal([]netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("10.0.0.1")}, utils.GetAddrsEnv(env, "ADDRS", nil))
+	req.Equal([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+	}, utils.GetPrefixesEnv(env, "PREFIXES", nil))
+
+	modes := []cacheMode{"off", "memory", "redis"}
+	req.Equal(cacheMode("redis"), utils.GetEnumEnv(env, "MODE", modes, "memory"))
+	req.Equal(cacheMode("memory"), utils.GetEnumEnv(env, "MISSING", modes, "memory"))
+
+	req.Equal(map[string]string{"team": "core", "tier": "web", "query": "a=b"}, utils.GetMapEnv(env, "LABELS", nil))
+
+	req.Equal(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), utils.GetTimeEnv(env, "START", time.Time{}))
+	req.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), utils.GetTimeEnv(env, "DAY", time.Time{}, time.DateOnly))
+	req.Equal("Europe/Belgrade", utils.GetLocationEnv(env, "TZ", time.UTC).String())
+	req.Equal(slog.LevelWarn+2, utils.GetLogLevelEnv(env, "LEVEL", slog.LevelInfo))
+	req.Equal(512*utils.MiB, utils.GetMemorySizeEnv(env, "CACHE", 0))
+
+	req.Equal("SHOUT", utils.GetEnvFunc(env, "UPPER", func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}, ""))
+}
+
+func TestTypedEnv_Errors(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	env := mapEnv(map[string]string{
+		"URL":      "/relative",
+		"ADDRS":    "127.0.0.1,nope",
+		"PREFIXES": "10.0.0.0/33",
+		"MODE":     "disk",
+		"LABELS":   "team=core,broken",
+		"START":    "yesterday",
+		"TZ":       "Mars/Olympus",
+		"LEVEL":    "loud",
+		"CACHE":    "12 parsecs",
+		"DSN":      "postgres://app:hunter2@db:5432%zz/app",
+	})
+
+	var parseErr *utils.EnvParseError
+
+	_, ok, err := utils.LookupURLEnv(env, "URL")
+	req.True(ok)
+	req.ErrorIs(err, utils.ErrRelativeURL)
+	req.ErrorAs(err, &parseErr)
+	req.Equal("url", parseErr.Type)
+
+	// credentials in a malformed DSN stay out of the message
+	_, _, err = utils.LookupURLEnv(env, "DSN")
+	req.ErrorAs(err, &parseErr)
+	req.NotContains(err.Error(), "hunter2")
+
+	_, _, err = utils.LookupAddrsEnv(env, "ADDRS")
+	req.ErrorContains(err, "element 1")
+	req.ErrorAs(err, &parseErr)
+	req.Equal("ip list", parseErr.Type)
+	req.NotContains(err.Error(), "nope")
+
+	_, _, err = utils.LookupPrefixesEnv(env, "PREFIXES")
+	req.Error(err)
+
+	_, _, err = utils.LookupEnumEnv(env, "MODE", []cacheMode{"off", "memory"})
+	req.ErrorIs(err, utils.ErrNotAllowed)
+	req.ErrorContains(err, "expected one of off, memory")
+
+	_, _, err = utils.LookupMapEnv(env, "LABELS")
+	req.ErrorIs(err, utils.ErrMalformedPair)
+
+	_, _, err = utils.LookupTimeEnv(env, "START")
+	req.Error(err)
+
+	_, _, err = utils.LookupLocationEnv(env, "TZ")
+	req.Error(err)
+
+	_, _, err = utils.LookupLogLevelEnv(env, "LEVEL")
+	req.Error(err)
+
+	_, _, err = utils.LookupMemorySizeEnv(env, "CACHE")
+	req.ErrorIs(err, utils.ErrInvalidMemorySize)
+
+	req.Panics(func() { _ = utils.GetLogLevelEnv(env, "LEVEL", slog.LevelInfo) })
+
+	// causes are reported without the values that were parsed
+	lookupErr := func(_ any, _ bool, err error) error { return err }
+
+	for expected, err := range map[string]error{
+		"invalid url value for DSN: parse: invalid port after host":                                         lookupErr(utils.LookupURLEnv(env, "DSN")),
+		"invalid ip list value for ADDRS: element 1: ParseAddr: unable to parse IP":                         lookupErr(utils.LookupAddrsEnv(env, "ADDRS")),
+		"invalid cidr list value for PREFIXES: element 0: netip.ParsePrefix: prefix length out of range":    lookupErr(utils.LookupPrefixesEnv(env, "PREFIXES")),
+		"invalid map value for LABELS: expected key=value":                                                  lookupErr(utils.LookupMapEnv(env, "LABELS")),
+		"invalid location value for TZ: unknown time zone":                                                  lookupErr(utils.LookupLocationEnv(env, "TZ")),
+		"invalid log level value for LEVEL: slog: level string: unknown name":                               lookupErr(utils.LookupLogLevelEnv(env, "LEVEL")),
+		"invalid size value for CACHE: invalid memory size":                                                 lookupErr(utils.LookupMemorySizeEnv(env, "CACHE")),
+		`invalid time value for START: parsing time as "2006-01-02T15:04:05Z07:00": cannot parse as "2006"`: lookupErr(utils.LookupTimeEnv(env, "START")),
+	} {
+		req.EqualError(err, expected)
+	}
+
+	_, ok, err = utils.LookupEnvFunc(env, "MISSING", netip.ParseAddr)
+	req.False(ok)
+	req.NoError(err)
+}
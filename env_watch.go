@@ -0,0 +1,386 @@
+package utils
+
+import (
+	"context"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/CodeLieutenant/utils/signals"
+)
+
+const (
+	DefaultWatchInterval = 2 * time.Second
+	DefaultWatchSignal   = "SIGHUP"
+)
+
+// EnvChange describes a single variable that changed during a reload
+type EnvChange struct {
+	Key     string
+	Old     string
+	New     string
+	Removed bool
+}
+
+// WatchOptions configures WatchingEnvProvider.Watch
+type WatchOptions struct {
+	// OnError is called when a reload fails, the previous values stay in place
+	OnError func(error)
+	// Signal triggers a reload, resolved through signals.Get. Defaults to DefaultWatchSignal, "-" disables it.
+	Signal string
+	// Interval between checks of the files on disk. Defaults to DefaultWatchInterval, a negative value disables polling.
+	Interval time.Duration
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// WatchingEnvProvider serves the dotenv cascade (see LoadDotEnv) from memory and reloads it when the files
+// change on disk or the process receives SIGHUP. It never touches the process environment, use NewWatchingEnv
+// to stack it below the OS provider with the usual precedence.
+//
+// Values are swapped atomically, readers always see a consistent snapshot.
+type WatchingEnvProvider struct {
+	values      atomic.Pointer[map[string]string]
+	subscribers map[string]map[uint64]func(old, new string)
+	channels    map[uint64]chan EnvChange
+	overrides   map[string]string
+	stamps      map[string]fileStamp
+	report      DotEnvReport
+	files       []string
+	nextID      uint64
+	reloadMu    sync.Mutex
+	// notifyMu is taken before reloadMu is released, so subscribers see changes in the order they were applied
+	notifyMu sync.Mutex
+	mu       sync.RWMutex
+}
+
+// NewWatchingEnv returns an Env reading the process environment first and the watched dotenv cascade second,
+// the watcher still has to be started with Watch. Do not also load the cascade with NewEnv(false) or LoadEnv:
+// values copied into the process environment would shadow every reload.
+//
+//	env, watcher, err := utils.NewWatchingEnv(utils.DotEnvOptions{})
+//	go watcher.Watch(ctx)
+func NewWatchingEnv(opts DotEnvOptions) (Env, *WatchingEnvProvider, error) {
+	watcher, err := NewWatchingEnvProvider(opts)
+	if err != nil {
+		return Env{}, nil, err
+	}
+
+	return Env{EnvProvider: NewLayeredEnvProvider(
+		EnvLayer{Name: "os", Provider: OSEnvProvider{}},
+		EnvLayer{Name: "dotenv", Provider: watcher},
+	)}, watcher, nil
+}
+
+// NewWatchingEnvProvider reads the dotenv cascade described by opts
+func NewWatchingEnvProvider(opts DotEnvOptions) (*WatchingEnvProvider, error) {
+	environment := opts.Environment
+	if environment == "" {
+		environment = detectEnvironment(opts.Root)
+	}
+
+	files := DotEnvFiles(environment)
+	for i, file := range files {
+		files[i] = filepath.Join(opts.Root, file)
+	}
+
+	p := &WatchingEnvProvider{
+		files:       files,
+		subscribers: make(map[string]map[uint64]func(old, new string)),
+		channels:    make(map[uint64]chan EnvChange),
+		overrides:   make(map[string]string),
+		report:      DotEnvReport{Root: opts.Root, Environment: environment},
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *WatchingEnvProvider) Get(key string) (string, bool) {
+	v, ok := (*p.values.Load())[key]
+
+	return v, ok
+}
+
+// Set overrides key in memory, the override survives reloads
+func (p *WatchingEnvProvider) Set(key, value string) {
+	p.reloadMu.Lock()
+	p.overrides[key] = value
+	current := *p.values.Load()
+	next := maps.Clone(current)
+	next[key] = value
+	p.values.Store(&next)
+	p.notifyMu.Lock()
+	p.reloadMu.Unlock()
+
+	defer p.notifyMu.Unlock()
+
+	p.notify(diffEnv(current, next))
+}
+
+// Keys returns the currently loaded keys
+func (p *WatchingEnvProvider) Keys() []string {
+	return slices.Sorted(maps.Keys(*p.values.Load()))
+}
+
+// Report describes the files used by the last successful reload
+func (p *WatchingEnvProvider) Report() DotEnvReport {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	report := p.report
+	report.Loaded = slices.Clone(report.Loaded)
+
+	return report
+}
+
+// Subscribe calls fn with the old and new value whenever key changes, a removed key is reported as an empty string.
+// Handlers run on the goroutine performing the reload, one change at a time and in order, so they must not
+// call Set or Reload themselves. The returned function removes the subscription.
+func (p *WatchingEnvProvider) Subscribe(key string, fn func(old, new string)) func() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+
+	if p.subscribers[key] == nil {
+		p.subscribers[key] = make(map[uint64]func(old, new string))
+	}
+
+	p.subscribers[key][id] = fn
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subscribers[key], id)
+	}
+}
+
+// Changes returns a channel receiving every change, events are dropped when the buffer is full.
+// The returned function unsubscribes and closes the channel.
+func (p *WatchingEnvProvider) Changes(buffer int) (<-chan EnvChange, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+	ch := make(chan EnvChange, buffer)
+	p.channels[id] = ch
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.channels, id)
+			close(ch)
+		})
+	}
+}
+
+// Reload re-reads the dotenv files and notifies subscribers about changed keys.
+// On error the previous values are kept.
+func (p *WatchingEnvProvider) Reload() error {
+	p.reloadMu.Lock()
+
+	next := make(map[string]string)
+	stamps := make(map[string]fileStamp, len(p.files))
+	loaded := make([]string, 0, len(p.files))
+
+	for _, file := range p.files {
+		if stamp, ok := statFile(file); ok {
+			stamps[file] = stamp
+		}
+	}
+
+	// Failed files are not retried until they change again
+	p.stamps = stamps
+
+	// Lowest precedence first, so more specific files override
+	for _, file := range slices.Backward(p.files) {
+		if _, ok := stamps[file]; !ok {
+			continue
+		}
+
+		values, err := godotenv.Read(file)
+		if err != nil {
+			p.reloadMu.Unlock()
+
+			return err
+		}
+
+		maps.Copy(next, values)
+		loaded = append(loaded, file)
+	}
+
+	maps.Copy(next, p.overrides)
+	slices.Reverse(loaded)
+
+	var current map[string]string
+	if old := p.values.Load(); old != nil {
+		current = *old
+	}
+
+	p.values.Store(&next)
+	p.report.Loaded = loaded
+	p.notifyMu.Lock()
+	p.reloadMu.Unlock()
+
+	defer p.notifyMu.Unlock()
+
+	if current != nil {
+		p.notify(diffEnv(current, next))
+	}
+
+	return nil
+}
+
+// Watch polls the files and listens for the reload signal until ctx is cancelled
+func (p *WatchingEnvProvider) Watch(ctx context.Context, opts ...WatchOptions) error {
+	var o WatchOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.Interval == 0 {
+		o.Interval = DefaultWatchInterval
+	}
+
+	if o.Signal == "" {
+		o.Signal = DefaultWatchSignal
+	}
+
+	var sigCh chan os.Signal
+
+	if o.Signal != "-" {
+		sig, err := signals.Get(o.Signal)
+		if err != nil {
+			return err
+		}
+
+		sigCh = make(chan os.Signal, 1)
+		signal.Notify(sigCh, sig)
+
+		defer signal.Stop(sigCh)
+	}
+
+	var tick <-chan time.Time
+
+	if o.Interval > 0 {
+		ticker := time.NewTicker(o.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	reload := func() {
+		if err := p.Reload(); err != nil && o.OnError != nil {
+			o.OnError(err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sigCh:
+			reload()
+		case <-tick:
+			if p.changedOnDisk() {
+				reload()
+			}
+		}
+	}
+}
+
+func (p *WatchingEnvProvider) changedOnDisk() bool {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	for _, file := range p.files {
+		stamp, exists := statFile(file)
+		previous, existed := p.stamps[file]
+
+		if exists != existed || !stamp.modTime.Equal(previous.modTime) || stamp.size != previous.size {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *WatchingEnvProvider) notify(changes []EnvChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	type call struct {
+		fn     func(old, new string)
+		change EnvChange
+	}
+
+	calls := make([]call, 0, len(changes))
+
+	p.mu.RLock()
+	for _, change := range changes {
+		for _, fn := range p.subscribers[change.Key] {
+			calls = append(calls, call{fn: fn, change: change})
+		}
+
+		// Sends never block, so they are safe under the lock guarding close
+		for _, ch := range p.channels {
+			select {
+			case ch <- change:
+			default:
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	// Handlers run without the lock so they may subscribe or unsubscribe
+	for _, c := range calls {
+		c.fn(c.change.Old, c.change.New)
+	}
+}
+
+func statFile(path string) (fileStamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return fileStamp{}, false
+	}
+
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, true
+}
+
+func diffEnv(current, next map[string]string) []EnvChange {
+	var changes []EnvChange
+
+	for _, key := range slices.Sorted(maps.Keys(next)) {
+		old, existed := current[key]
+		if !existed || old != next[key] {
+			changes = append(changes, EnvChange{Key: key, Old: old, New: next[key]})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(current)) {
+		if _, exists := next[key]; !exists {
+			changes = append(changes, EnvChange{Key: key, Old: current[key], Removed: true})
+		}
+	}
+
+	return changes
+}
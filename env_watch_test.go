@@ -0,0 +1,202 @@
+package utils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func TestWatchingEnvProvider_Reload(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	writeDotEnv(t, dir, map[string]string{
+		".env":       "FEATURE=false\nSECRET=v1\nREMOVED=x\n",
+		".env.local": "SECRET=v1-local\n",
+	})
+
+	p, err := utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: dir, Environment: utils.EnvProd})
+	req.NoError(err)
+	req.Equal([]string{filepath.Join(dir, ".env.local"), filepath.Join(dir, ".env")}, p.Report().Loaded)
+	req.Equal([]string{"FEATURE", "REMOVED", "SECRET"}, p.Keys())
+
+	env := utils.Env{EnvProvider: p}
+	req.False(utils.GetBoolEnv(env, "FEATURE", true))
+	req.Equal("v1-local", utils.GetEnv(env, "SECRET", ""))
+
+	var (
+		mu      sync.Mutex
+		secrets [][2]string
+	)
+
+	unsubscribe := p.Subscribe("SECRET", func(old, new string) {
+		mu.Lock()
+		defer mu.Unlock()
+		secrets = append(secrets, [2]string{old, new})
+	})
+
+	changes, stop := p.Changes(10)
+	defer stop()
+
+	writeDotEnv(t, dir, map[string]string{
+		".env":                  "FEATURE=true\nSECRET=v2\n",
+		".env.production.local": "SECRET=v2-prod\n",
+	})
+	req.NoError(p.Reload())
+
+	req.True(utils.GetBoolEnv(env, "FEATURE", false))
+	req.Equal("v2-prod", utils.GetEnv(env, "SECRET", ""))
+	req.Equal([][2]string{{"v1-local", "v2-prod"}}, secrets)
+
+	req.Equal(utils.EnvChange{Key: "FEATURE", Old: "false", New: "true"}, <-changes)
+	req.Equal(utils.EnvChange{Key: "SECRET", Old: "v1-local", New: "v2-prod"}, <-changes)
+	req.Equal(utils.EnvChange{Key: "REMOVED", Old: "x", Removed: true}, <-changes)
+
+	// unsubscribed handlers are not called
+	unsubscribe()
+	p.Set("SECRET", "manual")
+	req.Len(secrets, 1)
+	req.Equal(utils.EnvChange{Key: "SECRET", Old: "v2-prod", New: "manual"}, <-changes)
+
+	// overrides survive reloads
+	req.NoError(p.Reload())
+	req.Equal("manual", utils.GetEnv(env, "SECRET", ""))
+}
+
+func TestWatchingEnvProvider_ReloadErrorKeepsValues(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	writeDotEnv(t, dir, map[string]string{".env": "KEEP=yes\n"})
+
+	p, err := utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.NoError(err)
+
+	writeDotEnv(t, dir, map[string]string{".env": "INVALID_LINE_WITHOUT_EQUALS\n"})
+	req.Error(p.Reload())
+
+	v, ok := p.Get("KEEP")
+	req.True(ok)
+	req.Equal("yes", v)
+
+	_, err = utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.Error(err)
+}
+
+func TestWatchingEnvProvider_Watch(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	writeDotEnv(t, dir, map[string]string{".env": "TOGGLE=a\n"})
+
+	p, err := utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.NoError(err)
+
+	changes, stop := p.Changes(1)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- p.Watch(ctx, utils.WatchOptions{Interval: 5 * time.Millisecond, Signal: "-"})
+	}()
+
+	req.NoError(os.WriteFile(filepath.Join(dir, ".env.local"), []byte("TOGGLE=bb\n"), 0o600))
+
+	select {
+	case change := <-changes:
+		req.Equal(utils.EnvChange{Key: "TOGGLE", Old: "a", New: "bb"}, change)
+	case <-time.After(5 * time.Second):
+		req.Fail("no change detected")
+	}
+
+	cancel()
+	req.ErrorIs(<-done, context.Canceled)
+}
+
+func TestWatchingEnvProvider_WatchInvalidSignal(t *testing.T) {
+	t.Parallel()
+
+	p, err := utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: t.TempDir(), Environment: utils.EnvDev})
+	require.NoError(t, err)
+	require.Error(t, p.Watch(t.Context(), utils.WatchOptions{Signal: "SIGNOPE"}))
+}
+
+func TestNewWatchingEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	unsetEnv(t, "WATCHING_ENV_OS")
+	req.NoError(os.Setenv("WATCHING_ENV_OS", "from-os"))
+
+	writeDotEnv(t, dir, map[string]string{".env": "WATCHING_ENV_OS=from-file\nWATCHING_ENV_FILE=v1\n"})
+
+	env, watcher, err := utils.NewWatchingEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.NoError(err)
+	req.Equal("from-os", utils.GetEnv(env, "WATCHING_ENV_OS", ""))
+	req.Equal("v1", utils.GetEnv(env, "WATCHING_ENV_FILE", ""))
+
+	// reloads are visible through the Env, the process environment is left alone
+	writeDotEnv(t, dir, map[string]string{".env": "WATCHING_ENV_FILE=v2\n"})
+	req.NoError(watcher.Reload())
+	req.Equal("v2", utils.GetEnv(env, "WATCHING_ENV_FILE", ""))
+
+	_, ok := os.LookupEnv("WATCHING_ENV_FILE")
+	req.False(ok)
+
+	writeDotEnv(t, dir, map[string]string{".env": "INVALID_LINE_WITHOUT_EQUALS\n"})
+
+	_, _, err = utils.NewWatchingEnv(utils.DotEnvOptions{Root: dir, Environment: utils.EnvDev})
+	req.Error(err)
+}
+
+func TestWatchingEnvProvider_NotifiesInOrder(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	p, err := utils.NewWatchingEnvProvider(utils.DotEnvOptions{Root: t.TempDir(), Environment: utils.EnvDev})
+	req.NoError(err)
+
+	var (
+		mu    sync.Mutex
+		calls [][2]string
+		wg    sync.WaitGroup
+	)
+
+	p.Subscribe("COUNTER", func(old, new string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, [2]string{old, new})
+	})
+
+	for i := range 50 {
+		wg.Go(func() {
+			p.Set("COUNTER", strconv.Itoa(i))
+		})
+	}
+
+	wg.Wait()
+
+	// every change starts where the previous one ended and the last one matches the current value
+	req.Len(calls, 50)
+
+	for i := 1; i < len(calls); i++ {
+		req.Equal(calls[i-1][1], calls[i][0], i)
+	}
+
+	current, _ := p.Get("COUNTER")
+	req.Equal(current, calls[len(calls)-1][1])
+}
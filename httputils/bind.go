@@ -0,0 +1,135 @@
+package httputils
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Oudwins/zog"
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// Validator is satisfied by zog schemas such as zog.Struct(zog.Shape{...}) or zog.Slice(...)
+	Validator interface {
+		Validate(dataPtr any, options ...zog.ExecOption) zog.ZogIssueMap
+	}
+
+	// BindOptions selects where Bind reads values from besides the body
+	BindOptions struct {
+		// Decoders defaults to DefaultDecoders
+		Decoders *Decoders
+		// Query fills fields from the query string, after the body
+		Query bool
+		// URLParams fills fields from chi URL parameters, they take precedence over the body and the query string
+		URLParams bool
+	}
+)
+
+// Bind decodes the request into T, validates it with schema and writes the error response when either fails:
+// 415 or 400 like GetBody, 422 with Response.ValidationError for validation issues.
+// The body is optional when Query or URLParams is set, it is skipped when empty or without a Content-Type.
+// Query string and URL parameters are matched to fields like form values. schema may be nil.
+//
+//	user, ok := httputils.Bind[UpdateUser](w, r, updateUserSchema, httputils.BindOptions{URLParams: true})
+//	if !ok {
+//		return
+//	}
+func Bind[T any](w http.ResponseWriter, r *http.Request, schema Validator, opts ...BindOptions) (T, bool) {
+	var (
+		data T
+		opt  BindOptions
+	)
+
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if (!opt.Query && !opt.URLParams) || (r.Header.Get("Content-Type") != "" && hasBody(r)) {
+		value, err := DecodeBody[T](r, opt.Decoders)
+		if err != nil {
+			writeDecodeError(w, r, err)
+
+			return data, false
+		}
+
+		data = value
+	}
+
+	if opt.Query {
+		if err := decodeForm(r.URL.Query(), nil, &data); err != nil {
+			bindError(w, r, err, "invalid query string")
+
+			return data, false
+		}
+	}
+
+	if opt.URLParams {
+		if err := decodeForm(urlParams(r), nil, &data); err != nil {
+			bindError(w, r, err, "invalid URL parameter")
+
+			return data, false
+		}
+	}
+
+	if schema != nil {
+		if issues := schema.Validate(&data); len(issues) > 0 {
+			NewResponse(w).ValidationError(issues)
+
+			var empty T
+
+			return empty, false
+		}
+	}
+
+	return data, true
+}
+
+// hasBody reports whether r carries a body, peeking at it when the length is unknown (chunked requests)
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false
+	}
+
+	if r.ContentLength > 0 {
+		return true
+	}
+
+	buffered := bufio.NewReader(r.Body)
+	if _, err := buffered.Peek(1); err != nil {
+		return false
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{buffered, r.Body}
+
+	return true
+}
+
+func urlParams(r *http.Request) url.Values {
+	values := make(url.Values)
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" {
+				values.Set(key, rctx.URLParams.Values[i])
+			}
+		}
+	}
+
+	return values
+}
+
+func bindError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, ErrUnsupportedTarget) {
+		NewResponse(w).InternalServerError()
+
+		return
+	}
+
+	bodyErrorResponse(w, r).Error(http.StatusBadRequest, message+": "+err.Error())
+}
@@ -0,0 +1,116 @@
+package httputils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Oudwins/zog"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+type updateUser struct {
+	Name  string `json:"name"`
+	Role  string `json:"role"  form:"role"`
+	ID    int    `json:"-"     form:"id"`
+	Limit int    `json:"limit" form:"limit"`
+}
+
+var updateUserSchema = zog.Struct(zog.Shape{
+	"name":  zog.String().Min(3),
+	"limit": zog.Int().LTE(100),
+})
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	var bound updateUser
+
+	r := chi.NewRouter()
+	r.Put("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user, ok := Bind[updateUser](w, r, updateUserSchema, BindOptions{Query: true, URLParams: true})
+		if !ok {
+			return
+		}
+
+		bound = user
+		NewResponse(w).NoContent()
+	})
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		user, ok := Bind[updateUser](w, r, updateUserSchema, BindOptions{Query: true})
+		if !ok {
+			return
+		}
+
+		NewResponse(w).OK(user)
+	})
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := Bind[updateUser](w, r, nil); ok {
+			NewResponse(w).Created()
+		}
+	})
+
+	serve := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve(http.MethodPut, "/users/7?role=admin&limit=10", "application/json", `{"name":"john","limit":50}`)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, updateUser{ID: 7, Name: "john", Role: "admin", Limit: 10}, bound)
+
+	rr = serve(http.MethodPut, "/users/7", "application/json", `{"name":"jo","limit":500}`)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.Contains(t, rr.Body.String(), `"name":["string must contain at least 3 character(s)"]`)
+	require.Contains(t, rr.Body.String(), `"limit":["number must be less than or equal to 100"]`)
+
+	// the body is optional when binding the query string
+	rr = serve(http.MethodGet, "/users?name=jane&limit=3", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"name":"jane","role":"","limit":3}`, rr.Body.String())
+
+	// clients sending a default Content-Type without a body are treated the same
+	rr = serve(http.MethodGet, "/users?name=jane&limit=3", "application/json", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"name":"jane","role":"","limit":3}`, rr.Body.String())
+
+	chunked := httptest.NewRequest(http.MethodGet, "/users?name=jane&limit=3", io.NopCloser(strings.NewReader("")))
+	chunked.Header.Set("Content-Type", "application/json")
+	chunked.ContentLength = -1
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, chunked)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// a body of unknown length is still decoded
+	chunked = httptest.NewRequest(http.MethodPut, "/users/7", io.NopCloser(strings.NewReader(`{"name":"jack"}`)))
+	chunked.Header.Set("Content-Type", "application/json")
+	chunked.ContentLength = -1
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, chunked)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, "jack", bound.Name)
+
+	rr = serve(http.MethodGet, "/users?name=jane&limit=many", "", "")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "invalid query string")
+
+	rr = serve(http.MethodPut, "/users/abc", "application/json", `{"name":"john"}`)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Contains(t, rr.Body.String(), "invalid URL parameter")
+
+	rr = serve(http.MethodPost, "/users", "", "")
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	rr = serve(http.MethodPost, "/users", "application/x-www-form-urlencoded", "name=x")
+	require.Equal(t, http.StatusCreated, rr.Code)
+}
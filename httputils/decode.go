@@ -0,0 +1,187 @@
+package httputils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported Content-Type")
+	ErrUnsupportedTarget    = errors.New("unsupported decode target")
+)
+
+type (
+	// BodyDecoder decodes the body of r into v, a pointer
+	BodyDecoder interface {
+		Decode(r *http.Request, v any) error
+	}
+
+	// BodyDecoderFunc adapts a function to BodyDecoder
+	BodyDecoderFunc func(r *http.Request, v any) error
+
+	// Decoders maps media types to the BodyDecoder used for them
+	Decoders struct {
+		byType map[string]BodyDecoder
+		mu     sync.RWMutex
+	}
+
+	JSONDecoder struct{}
+
+	XMLDecoder struct{}
+
+	// NDJSONDecoder decodes newline delimited JSON into a pointer to a slice, see ReadNDJSON for streaming
+	NDJSONDecoder struct{}
+)
+
+// DefaultDecoders is used by GetBody, register decoders on it for additional media types
+var DefaultDecoders = NewDecoders()
+
+// NewDecoders handles JSON, form-urlencoded, multipart, XML and NDJSON bodies
+func NewDecoders() *Decoders {
+	return (&Decoders{byType: make(map[string]BodyDecoder)}).
+		Register("application/json", JSONDecoder{}).
+		Register("application/x-www-form-urlencoded", FormDecoder{}).
+		Register("multipart/form-data", MultipartDecoder{}).
+		Register("application/xml", XMLDecoder{}).
+		Register("text/xml", XMLDecoder{}).
+		Register("application/x-ndjson", NDJSONDecoder{}).
+		Register("application/ndjson", NDJSONDecoder{})
+}
+
+func (f BodyDecoderFunc) Decode(r *http.Request, v any) error {
+	return f(r, v)
+}
+
+// Register sets the decoder for mediaType, replacing an existing one
+func (d *Decoders) Register(mediaType string, decoder BodyDecoder) *Decoders {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.byType[strings.ToLower(mediaType)] = decoder
+
+	return d
+}
+
+// Lookup finds the decoder for a Content-Type header value, parameters such as charset are ignored.
+// Types without their own decoder fall back to their +json or +xml suffix, e.g. application/problem+json.
+func (d *Decoders) Lookup(contentType string) (BodyDecoder, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if decoder, ok := d.byType[mediaType]; ok {
+		return decoder, true
+	}
+
+	if idx := strings.LastIndexByte(mediaType, '+'); idx >= 0 {
+		decoder, ok := d.byType["application/"+mediaType[idx+1:]]
+
+		return decoder, ok
+	}
+
+	return nil, false
+}
+
+// MediaTypes lists the registered media types in alphabetical order
+func (d *Decoders) MediaTypes() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(d.byType))
+}
+
+// DecodeBody decodes the body of r with the decoder registered for its Content-Type, DefaultDecoders
+// when none are given. ErrUnsupportedMediaType is returned when there is no such decoder.
+func DecodeBody[T any](r *http.Request, decoders ...*Decoders) (T, error) {
+	var data T
+
+	registry := DefaultDecoders
+	if len(decoders) > 0 && decoders[0] != nil {
+		registry = decoders[0]
+	}
+
+	decoder, ok := registry.Lookup(r.Header.Get("Content-Type"))
+	if !ok {
+		return data, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, r.Header.Get("Content-Type"))
+	}
+
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(r.Body)
+
+	if err := decoder.Decode(r, &data); err != nil {
+		var empty T
+
+		return empty, err
+	}
+
+	return data, nil
+}
+
+func (JSONDecoder) Decode(r *http.Request, v any) error {
+	return decodeJSON(r.Body, v)
+}
+
+func (XMLDecoder) Decode(r *http.Request, v any) error {
+	return xml.NewDecoder(r.Body).Decode(v)
+}
+
+func (NDJSONDecoder) Decode(r *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: NDJSON needs a pointer to a slice, got %T", ErrUnsupportedTarget, v)
+	}
+
+	slice := rv.Elem()
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	for {
+		item := reflect.New(slice.Type().Elem())
+		if err := dec.Decode(item.Interface()); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+}
+
+// ReadNDJSON streams newline delimited JSON values from r, iteration stops after the first error
+func ReadNDJSON[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+
+		for {
+			var item T
+
+			err := dec.Decode(&item)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if !yield(item, err) || err != nil {
+				return
+			}
+		}
+	}
+}
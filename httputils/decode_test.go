@@ -0,0 +1,191 @@
+package httputils
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type signupForm struct {
+	Born     time.Time               `form:"born"`
+	Age      *int                    `form:"age"`
+	Avatar   *multipart.FileHeader   `form:"avatar"`
+	Name     string                  `json:"name"`
+	Tags     []string                `form:"tag"`
+	Files    []*multipart.FileHeader `form:"files"`
+	Ignored  string                  `form:"-"`
+	Score    float64
+	Accepted bool `form:"accepted"`
+}
+
+func newBodyRequest(contentType, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+
+	return r
+}
+
+func TestDecoders_Lookup(t *testing.T) {
+	t.Parallel()
+
+	d := NewDecoders()
+
+	for contentType, expected := range map[string]BodyDecoder{
+		"application/json":                  JSONDecoder{},
+		"Application/JSON; charset=UTF-8":   JSONDecoder{},
+		"application/problem+json":          JSONDecoder{},
+		"application/atom+xml":              XMLDecoder{},
+		"text/xml; charset=utf-8":           XMLDecoder{},
+		"application/x-ndjson":              NDJSONDecoder{},
+		"application/x-www-form-urlencoded": FormDecoder{},
+		"multipart/form-data; boundary=x":   MultipartDecoder{},
+	} {
+		decoder, ok := d.Lookup(contentType)
+		require.True(t, ok, contentType)
+		require.Equal(t, expected, decoder, contentType)
+	}
+
+	for _, contentType := range []string{"", "text/plain", "image/png", "application/json;;"} {
+		_, ok := d.Lookup(contentType)
+		require.False(t, ok, contentType)
+	}
+
+	d.Register("text/csv", BodyDecoderFunc(func(r *http.Request, v any) error {
+		data, err := io.ReadAll(r.Body)
+		*v.(*[]string) = strings.Split(string(data), ",") //nolint:forcetypeassert
+
+		return err
+	}))
+
+	values, err := DecodeBody[[]string](newBodyRequest("text/csv", "a,b"), d)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, values)
+
+	_, err = DecodeBody[[]string](newBodyRequest("text/csv", "a,b"))
+	require.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
+
+func TestDecodeBody_Form(t *testing.T) {
+	t.Parallel()
+
+	body := url.Values{
+		"name":     {"john"},
+		"age":      {"42"},
+		"tag":      {"a", "b"},
+		"born":     {"2000-01-02T00:00:00Z"},
+		"Score":    {"9.5"},
+		"accepted": {"on"},
+		"Ignored":  {"x"},
+	}
+
+	form, err := DecodeBody[signupForm](newBodyRequest("application/x-www-form-urlencoded", body.Encode()))
+	require.NoError(t, err)
+	require.Equal(t, "john", form.Name)
+	require.Equal(t, 42, *form.Age)
+	require.Equal(t, []string{"a", "b"}, form.Tags)
+	require.Equal(t, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), form.Born)
+	require.InDelta(t, 9.5, form.Score, 0)
+	require.True(t, form.Accepted)
+	require.Empty(t, form.Ignored)
+
+	values, err := DecodeBody[url.Values](newBodyRequest("application/x-www-form-urlencoded", "a=1&a=2"))
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"a": {"1", "2"}}, values)
+
+	_, err = DecodeBody[signupForm](newBodyRequest("application/x-www-form-urlencoded", "age=old"))
+	require.ErrorIs(t, err, ErrInvalidFormValue)
+	require.ErrorContains(t, err, "age")
+
+	_, err = DecodeBody[string](newBodyRequest("application/x-www-form-urlencoded", "a=1"))
+	require.ErrorIs(t, err, ErrUnsupportedTarget)
+}
+
+func TestDecodeBody_Multipart(t *testing.T) {
+	t.Parallel()
+
+	var body bytes.Buffer
+
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("name", "john"))
+	require.NoError(t, mw.WriteField("tag", "a"))
+
+	for _, name := range []string{"avatar", "files", "files"} {
+		part, err := mw.CreateFormFile(name, name+".txt")
+		require.NoError(t, err)
+		_, err = part.Write([]byte("content of " + name))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, mw.Close())
+
+	form, err := DecodeBody[signupForm](newBodyRequest(mw.FormDataContentType(), body.String()))
+	require.NoError(t, err)
+	require.Equal(t, "john", form.Name)
+	require.Equal(t, []string{"a"}, form.Tags)
+	require.Nil(t, form.Age)
+	require.Equal(t, "avatar.txt", form.Avatar.Filename)
+	require.Len(t, form.Files, 2)
+
+	file, err := form.Avatar.Open()
+	require.NoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.Equal(t, "content of avatar", string(content))
+}
+
+func TestDecodeBody_XMLAndNDJSON(t *testing.T) {
+	t.Parallel()
+
+	message, err := DecodeBody[ErrorMessage](newBodyRequest("application/xml", `<error><message>hi</message></error>`))
+	require.NoError(t, err)
+	require.Equal(t, "hi", message.Message)
+
+	items, err := DecodeBody[[]TestStruct](newBodyRequest("application/x-ndjson", "{\"name\":\"a\",\"value\":1}\n{\"name\":\"b\",\"value\":2}\n"))
+	require.NoError(t, err)
+	require.Equal(t, []TestStruct{{Name: "a", Value: 1}, {Name: "b", Value: 2}}, items)
+
+	_, err = DecodeBody[TestStruct](newBodyRequest("application/x-ndjson", "{}"))
+	require.ErrorIs(t, err, ErrUnsupportedTarget)
+
+	var names []string
+
+	for item, err := range ReadNDJSON[TestStruct](strings.NewReader("{\"name\":\"a\"}\n{\"name\":\"b\"}\n{\"oops\":1}\n{\"name\":\"c\"}")) {
+		if err != nil {
+			require.ErrorContains(t, err, "unknown field")
+
+			break
+		}
+
+		names = append(names, item.Name)
+	}
+
+	require.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestGetBody_UnsupportedMediaType(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	_, ok := GetBody[TestStruct](rr, newBodyRequest("application/yaml", "name: a"))
+	require.False(t, ok)
+	require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+
+	rr = httptest.NewRecorder()
+	_, ok = GetBody[TestStruct](rr, newBodyRequest("application/x-www-form-urlencoded", "value=x"))
+	require.False(t, ok)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	_, err := DecodeBody[TestStruct](newBodyRequest("application/yaml", ""))
+	require.ErrorIs(t, err, ErrUnsupportedMediaType)
+}
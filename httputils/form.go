@@ -0,0 +1,215 @@
+package httputils
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultMultipartMemory is the part of a multipart body kept in memory, the rest is stored in temporary files
+const DefaultMultipartMemory = 32 << 20
+
+var ErrInvalidFormValue = errors.New("invalid form value")
+
+type (
+	// FormDecoder decodes application/x-www-form-urlencoded bodies into a struct, a *url.Values
+	// or a *map[string][]string. Fields are matched by their form tag, then their json tag, then their name.
+	FormDecoder struct{}
+
+	// MultipartDecoder decodes multipart/form-data bodies like FormDecoder, fields of type
+	// *multipart.FileHeader or []*multipart.FileHeader receive the uploaded files
+	MultipartDecoder struct {
+		// MaxMemory defaults to DefaultMultipartMemory
+		MaxMemory int64
+	}
+)
+
+var (
+	fileHeaderType  = reflect.TypeFor[*multipart.FileHeader]()
+	fileHeadersType = reflect.TypeFor[[]*multipart.FileHeader]()
+)
+
+func (FormDecoder) Decode(r *http.Request, v any) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	return decodeForm(r.PostForm, nil, v)
+}
+
+func (d MultipartDecoder) Decode(r *http.Request, v any) error {
+	maxMemory := d.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = DefaultMultipartMemory
+	}
+
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return err
+	}
+
+	return decodeForm(r.MultipartForm.Value, r.MultipartForm.File, v)
+}
+
+func decodeForm(values url.Values, files map[string][]*multipart.FileHeader, v any) error {
+	switch target := v.(type) {
+	case *url.Values:
+		*target = values
+
+		return nil
+	case *map[string][]string:
+		*target = values
+
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: forms decode into a pointer to a struct, got %T", ErrUnsupportedTarget, v)
+	}
+
+	return decodeStruct(rv.Elem(), values, files)
+}
+
+func decodeStruct(rv reflect.Value, values url.Values, files map[string][]*multipart.FileHeader) error {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(fv, values, files); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := formName(field)
+		if name == "-" {
+			continue
+		}
+
+		switch field.Type {
+		case fileHeaderType:
+			if headers := files[name]; len(headers) > 0 {
+				fv.Set(reflect.ValueOf(headers[0]))
+			}
+
+			continue
+		case fileHeadersType:
+			if headers := files[name]; len(headers) > 0 {
+				fv.Set(reflect.ValueOf(headers))
+			}
+
+			continue
+		}
+
+		formValues, ok := values[name]
+		if !ok || len(formValues) == 0 {
+			continue
+		}
+
+		if err := setFormField(fv, formValues); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidFormValue, name, err)
+		}
+	}
+
+	return nil
+}
+
+func formName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+
+	return field.Name
+}
+
+func setFormField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Slice && !implementsTextUnmarshaler(fv) {
+		slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFormValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+
+		fv.Set(slice)
+
+		return nil
+	}
+
+	return setFormValue(fv, values[0])
+}
+
+func implementsTextUnmarshaler(fv reflect.Value) bool {
+	return fv.CanAddr() && fv.Addr().Type().Implements(reflect.TypeFor[encoding.TextUnmarshaler]())
+}
+
+func setFormValue(fv reflect.Value, value string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setFormValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+
+		return nil
+	}
+
+	if implementsTextUnmarshaler(fv) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)) //nolint:forcetypeassert
+	}
+
+	switch fv.Kind() { //nolint:exhaustive
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		// checkboxes are sent as "on"
+		b, err := strconv.ParseBool(value)
+		if err != nil && value != "on" {
+			return err
+		}
+
+		fv.SetBool(b || value == "on")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedTarget, fv.Type())
+	}
+
+	return nil
+}
@@ -1,18 +1,22 @@
 package httputils
 
 import (
-	"encoding/json"
+	"errors"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
-	"slices"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 type ErrorMessage struct {
-	Message string `json:"message"`
+	Message string `json:"message" xml:"message"`
+}
+
+func (e ErrorMessage) String() string {
+	return e.Message
 }
 
 // MiddlewareConfig controls which middlewares are applied
@@ -50,6 +54,9 @@ type RouterSetupOptions struct {
 	Middleware  *MiddlewareConfig
 
 	BeforeRun func(r *chi.Mux)
+
+	// Problems renders error responses of the router as RFC 9457 problem details, see ProblemDetails
+	Problems *ProblemTypes
 }
 
 // SetupRouter creates a chi router with configurable middlewares
@@ -85,7 +92,8 @@ func SetupRouter(opts *RouterSetupOptions) *chi.Mux {
 		r.Use(middleware.RealIP)
 	}
 	if opts.Middleware.AllowContentType {
-		r.Use(middleware.AllowContentType("application/json", "multipart/form-data"))
+		// Decoders registered on DefaultDecoders after the router is set up are not allowed
+		r.Use(middleware.AllowContentType(DefaultDecoders.MediaTypes()...))
 	}
 	if opts.Middleware.Compress {
 		r.Use(middleware.Compress(5, "brotli", "gzip", "deflate"))
@@ -93,6 +101,9 @@ func SetupRouter(opts *RouterSetupOptions) *chi.Mux {
 	if opts.Middleware.RequestSize {
 		r.Use(middleware.RequestSize(20 * 1024 * 1024))
 	}
+	if opts.Problems != nil {
+		r.Use(ProblemDetails(opts.Problems))
+	}
 
 	return r
 }
@@ -106,9 +117,7 @@ func ReadJSON[T any](r io.Reader) (T, error) {
 		}()
 	}
 
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields() // approximate RejectUnknownMembers
-	if err := dec.Decode(&data); err != nil {
+	if err := decodeJSON(r, &data); err != nil {
 		var empty T
 
 		return empty, err
@@ -117,37 +126,46 @@ func ReadJSON[T any](r io.Reader) (T, error) {
 	return data, nil
 }
 
-func GetBody[T any](w http.ResponseWriter, r *http.Request) (T, bool) {
-	switch r.Header.Get("Content-Type") {
-	case "application/json":
-		defer func(body io.ReadCloser) {
-			_ = body.Close()
-		}(r.Body)
-
-		value, err := ReadJSON[T](r.Body)
-		if err != nil {
-			goto errors
-		}
+// bodyErrorNegotiator keeps text/plain as the default for GetBody errors, JSON is used when the client prefers it
+var bodyErrorNegotiator = (&Negotiator{}).Register("text/plain", Text{}).Register("application/json", JSON{})
 
+// GetBody decodes the request body with DefaultDecoders. It answers 415 when the Content-Type has no decoder
+// and 400 when decoding fails, JSON errors carry their position and field, see JSONDecodeError.
+func GetBody[T any](w http.ResponseWriter, r *http.Request) (T, bool) {
+	value, err := DecodeBody[T](r)
+	if err == nil {
 		return value, true
-	default:
-		goto errors
 	}
 
-errors:
-	allowsJSON := slices.Contains(r.Header.Values("Accept"), "application/json")
-
-	if allowsJSON {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte(`{"error": "unsupported Content-Type"}`))
-	} else {
-		w.Header().Set("Content-Type", "text/plain")
-		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte(`unsupported Content-Type`))
-	}
+	writeDecodeError(w, r, err)
 
 	var t T
 
 	return t, false
 }
+
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		jsonErr     *JSONDecodeError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		bodyErrorResponse(w, r).Error(http.StatusUnsupportedMediaType, "unsupported Content-Type")
+	case errors.Is(err, ErrUnsupportedTarget):
+		slog.Error("failed to decode request body", "error", err)
+		NewResponse(w).InternalServerError()
+	case errors.As(err, &jsonErr):
+		bodyErrorResponse(w, r).JSONDecodeError(jsonErr)
+	case errors.As(err, &maxBytesErr):
+		bodyErrorResponse(w, r).Error(http.StatusRequestEntityTooLarge, "request body too large")
+	default:
+		bodyErrorResponse(w, r).Error(http.StatusBadRequest, "invalid body: "+err.Error())
+	}
+}
+
+// bodyErrorResponse renders request errors as text unless the client prefers JSON
+func bodyErrorResponse(w http.ResponseWriter, r *http.Request) Response {
+	return NewResponse(w).Negotiate(r, bodyErrorNegotiator)
+}
@@ -144,6 +144,31 @@ func TestSetupRouter(t *testing.T) {
 		require.IsType(t, &chi.Mux{}, router)
 	})
 
+	t.Run("AllowContentTypeDecoders", func(t *testing.T) {
+		t.Parallel()
+		router := SetupRouter(&RouterSetupOptions{Middleware: &MiddlewareConfig{AllowContentType: true}})
+		router.Post("/", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		for contentType, expected := range map[string]int{
+			"application/json":                  http.StatusNoContent,
+			"application/x-www-form-urlencoded": http.StatusNoContent,
+			"multipart/form-data; boundary=x":   http.StatusNoContent,
+			"application/xml":                   http.StatusNoContent,
+			"text/xml; charset=utf-8":           http.StatusNoContent,
+			"application/x-ndjson":              http.StatusNoContent,
+			"text/csv":                          http.StatusUnsupportedMediaType,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body"))
+			req.Header.Set("Content-Type", contentType)
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			require.Equal(t, expected, rr.Code, contentType)
+		}
+	})
+
 	t.Run("WithNoMiddlewares", func(t *testing.T) {
 		t.Parallel()
 		opts := &RouterSetupOptions{
@@ -299,6 +324,18 @@ func TestGetBody(t *testing.T) {
 		require.Equal(t, http.StatusOK, rr.Code)
 	})
 
+	t.Run("JSONWithCharset", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"test","value":42}`))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+		rr := httptest.NewRecorder()
+
+		result, success := GetBody[TestStruct](rr, req)
+		require.True(t, success)
+		require.Equal(t, TestStruct{Name: "test", Value: 42}, result)
+	})
+
 	t.Run("InvalidJSONContent", func(t *testing.T) {
 		t.Parallel()
 		invalidJSON := `{"name":"test","value":}`
@@ -314,7 +351,12 @@ func TestGetBody(t *testing.T) {
 		require.Equal(t, 0, result.Value)
 		require.Equal(t, http.StatusBadRequest, rr.Code)
 		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
-		require.Contains(t, rr.Body.String(), `"error": "unsupported Content-Type"`)
+		require.JSONEq(t, `{
+			"message": "invalid JSON: invalid character '}' looking for beginning of value at line 1, column 24",
+			"offset": 23,
+			"line": 1,
+			"column": 24
+		}`, rr.Body.String())
 	})
 
 	t.Run("UnsupportedContentType", func(t *testing.T) {
@@ -329,9 +371,9 @@ func TestGetBody(t *testing.T) {
 		require.False(t, success)
 		require.Empty(t, result.Name)
 		require.Equal(t, 0, result.Value)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
 		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
-		require.Contains(t, rr.Body.String(), `"error": "unsupported Content-Type"`)
+		require.JSONEq(t, `{"message":"unsupported Content-Type"}`, rr.Body.String())
 	})
 
 	t.Run("UnsupportedContentTypeNoJSONAccept", func(t *testing.T) {
@@ -346,11 +388,35 @@ func TestGetBody(t *testing.T) {
 		require.False(t, success)
 		require.Empty(t, result.Name)
 		require.Equal(t, 0, result.Value)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
 		require.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
 		require.Equal(t, "unsupported Content-Type", rr.Body.String())
 	})
 
+	t.Run("UnsupportedContentTypeProblem", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("a,b"))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Accept", "application/json")
+
+		rr := httptest.NewRecorder()
+
+		ProblemDetails(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, success := GetBody[TestStruct](w, r)
+			require.False(t, success)
+		})).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+		require.JSONEq(t, `{
+			"type": "about:blank",
+			"title": "Unsupported Media Type",
+			"status": 415,
+			"detail": "unsupported Content-Type",
+			"instance": "/upload"
+		}`, rr.Body.String())
+	})
+
 	t.Run("NoContentType", func(t *testing.T) {
 		t.Parallel()
 		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
@@ -362,9 +428,9 @@ func TestGetBody(t *testing.T) {
 		require.False(t, success)
 		require.Empty(t, result.Name)
 		require.Equal(t, 0, result.Value)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
 		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
-		require.Contains(t, rr.Body.String(), `"error": "unsupported Content-Type"`)
+		require.JSONEq(t, `{"message":"unsupported Content-Type"}`, rr.Body.String())
 	})
 
 	t.Run("MultipleAcceptHeaders", func(t *testing.T) {
@@ -381,9 +447,31 @@ func TestGetBody(t *testing.T) {
 		require.False(t, success)
 		require.Empty(t, result.Name)
 		require.Equal(t, 0, result.Value)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
 		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
-		require.Contains(t, rr.Body.String(), `"error": "unsupported Content-Type"`)
+		require.JSONEq(t, `{"message":"unsupported Content-Type"}`, rr.Body.String())
+	})
+
+	t.Run("AcceptQValues", func(t *testing.T) {
+		t.Parallel()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("Accept", "text/plain;q=0.5, application/json; charset=utf-8")
+
+		rr := httptest.NewRecorder()
+
+		_, success := GetBody[TestStruct](rr, req)
+		require.False(t, success)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.Equal(t, "Accept", rr.Header().Get("Vary"))
+
+		req.Header.Set("Accept", "application/json;q=0.1, */*")
+
+		rr = httptest.NewRecorder()
+
+		_, success = GetBody[TestStruct](rr, req)
+		require.False(t, success)
+		require.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
 	})
 }
 
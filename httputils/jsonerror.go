@@ -0,0 +1,224 @@
+package httputils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyBody    = errors.New("empty body")
+	ErrTrailingData = errors.New("unexpected data after the JSON value")
+)
+
+// JSONDecodeError describes why a JSON body could not be decoded. Offset is the position of the offending byte,
+// the last byte of the value for type errors. Line and Column are 1-based and zero when the position is unknown. Field is the dotted path of the offending
+// field for type errors (e.g. items.1.price) and the name of an unknown field.
+type JSONDecodeError struct {
+	Err          error
+	Field        string
+	Offset       int64
+	Line, Column int
+}
+
+func (e *JSONDecodeError) Error() string {
+	var (
+		typeErr *json.UnmarshalTypeError
+		message string
+	)
+
+	switch {
+	case errors.As(e.Err, &typeErr) && e.Field != "":
+		message = "field " + strconv.Quote(e.Field) + " must be " + jsonTypeName(typeErr) + ", got " + typeErr.Value
+	case errors.As(e.Err, &typeErr):
+		message = "body must be " + jsonTypeName(typeErr) + ", got " + typeErr.Value
+	case e.Field != "":
+		message = "unknown field " + strconv.Quote(e.Field)
+	case errors.Is(e.Err, ErrEmptyBody), errors.Is(e.Err, ErrTrailingData):
+		message = e.Err.Error()
+	case errors.Is(e.Err, io.ErrUnexpectedEOF):
+		message = "invalid JSON: unexpected end of input"
+	default:
+		message = "invalid JSON: " + strings.TrimPrefix(e.Err.Error(), "json: ")
+	}
+
+	if e.Line > 0 {
+		message += fmt.Sprintf(" at line %d, column %d", e.Line, e.Column)
+	}
+
+	return message
+}
+
+func (e *JSONDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeJSON decodes a single JSON value from r into v, anything but whitespace after it is an error.
+// Failures are reported as *JSONDecodeError, errors of r are returned as they are.
+func decodeJSON(r io.Reader, v any) error {
+	// the bytes read are only kept to locate errors, the decoder buffers the value anyway
+	src := &recordingReader{r: r}
+
+	dec := json.NewDecoder(src)
+	dec.DisallowUnknownFields() // approximate RejectUnknownMembers
+
+	err := dec.Decode(v)
+
+	switch {
+	case src.err != nil:
+		return src.err
+	case errors.Is(err, io.EOF):
+		return &JSONDecodeError{Err: ErrEmptyBody}
+	case err != nil:
+		return newJSONDecodeError(src.data.Bytes(), err)
+	}
+
+	offset := dec.InputOffset()
+
+	_, err = dec.Token()
+
+	switch {
+	case src.err != nil:
+		return src.err
+	case errors.Is(err, io.EOF):
+		return nil
+	}
+
+	// point at the first byte of the trailing data
+	data := src.data.Bytes()
+	offset += int64(len(data[offset:]) - len(bytes.TrimLeft(data[offset:], " \t\r\n")))
+
+	return jsonErrorAt(data, &JSONDecodeError{Err: ErrTrailingData}, offset+1)
+}
+
+// recordingReader keeps the bytes read from r and the first error other than io.EOF
+type recordingReader struct {
+	r    io.Reader
+	err  error
+	data bytes.Buffer
+}
+
+func (r *recordingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	_, _ = r.data.Write(p[:n])
+
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+
+	return n, err
+}
+
+func newJSONDecodeError(data []byte, err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return jsonErrorAt(data, &JSONDecodeError{Err: err}, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return jsonErrorAt(data, &JSONDecodeError{Err: err, Field: typeErr.Field}, typeErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return jsonErrorAt(data, &JSONDecodeError{Err: err}, int64(len(data)))
+	}
+
+	// encoding/json reports unknown fields with a plain error, without a position
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if unquoted, unquoteErr := strconv.Unquote(field); unquoteErr == nil {
+			field = unquoted
+		}
+
+		return &JSONDecodeError{Err: err, Field: field}
+	}
+
+	return &JSONDecodeError{Err: err}
+}
+
+// jsonErrorAt sets the position of the byte before offset, encoding/json reports offsets after the offending byte
+func jsonErrorAt(data []byte, err *JSONDecodeError, offset int64) *JSONDecodeError {
+	offset = min(max(offset, 1), int64(len(data)))
+	before := data[:offset-1]
+
+	err.Offset = offset - 1
+	err.Line = bytes.Count(before, []byte{'\n'}) + 1
+	err.Column = len(before) - bytes.LastIndexByte(before, '\n')
+
+	return err
+}
+
+func jsonTypeName(err *json.UnmarshalTypeError) string {
+	if err.Type == nil {
+		return "valid"
+	}
+
+	switch err.Type.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return err.Type.String()
+	}
+}
+
+// jsonErrorMessage is the body written by Response.JSONDecodeError
+type jsonErrorMessage struct {
+	Offset  *int64 `json:"offset,omitempty" xml:"offset,omitempty"`
+	Message string `json:"message"          xml:"message"`
+	Field   string `json:"field,omitempty"  xml:"field,omitempty"`
+	Line    int    `json:"line,omitempty"   xml:"line,omitempty"`
+	Column  int    `json:"column,omitempty" xml:"column,omitempty"`
+}
+
+func (m jsonErrorMessage) String() string {
+	return m.Message
+}
+
+// JSONDecodeError answers 400 with the message, position and field of err
+func (r Response) JSONDecodeError(err *JSONDecodeError) {
+	message := jsonErrorMessage{Message: err.Error(), Field: err.Field}
+	if err.Line > 0 {
+		message.Offset, message.Line, message.Column = &err.Offset, err.Line, err.Column
+	}
+
+	if r.problems != nil {
+		problem := r.problems.ForStatus(http.StatusBadRequest)
+		problem.Detail = message.Message
+
+		if problem.Extensions == nil {
+			problem.Extensions = make(map[string]any, 4)
+		}
+
+		if message.Field != "" {
+			problem.Extensions["field"] = message.Field
+		}
+
+		if message.Line > 0 {
+			problem.Extensions["offset"] = err.Offset
+			problem.Extensions["line"] = message.Line
+			problem.Extensions["column"] = message.Column
+		}
+
+		r.Problem(problem)
+
+		return
+	}
+
+	r.encoder.Encode(r.w, http.StatusBadRequest, message)
+}
@@ -0,0 +1,175 @@
+package httputils
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type order struct {
+	Customer struct {
+		Name string `json:"name"`
+	} `json:"customer"`
+	Items []struct {
+		Price float64 `json:"price"`
+	} `json:"items"`
+}
+
+func TestReadJSON_DecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+		field   string
+		line    int
+		column  int
+	}{
+		{
+			name:    "Syntax",
+			body:    "{\n  \"customer\": {\"name\": \"a\",}\n}",
+			message: "invalid JSON: invalid character '}' looking for beginning of object key string at line 2, column 28",
+			line:    2,
+			column:  28,
+		},
+		{
+			name:    "Type",
+			body:    `{"items":[{"price":1},{"price":"free"}]}`,
+			message: `field "items.1.price" must be a number, got string at line 1, column 37`,
+			field:   "items.1.price",
+			line:    1,
+			column:  37,
+		},
+		{
+			name:    "TopLevelType",
+			body:    `[]`,
+			message: "body must be an object, got array at line 1, column 1",
+			line:    1,
+			column:  1,
+		},
+		{
+			name:    "UnknownField",
+			body:    `{"customer":{"name":"a","email":"b"}}`,
+			message: `unknown field "email"`,
+			field:   "email",
+		},
+		{
+			name:    "TrailingData",
+			body:    "{\"items\":[]}\n  {\"items\":[]}",
+			message: "unexpected data after the JSON value at line 2, column 3",
+			line:    2,
+			column:  3,
+		},
+		{
+			name:    "UnexpectedEnd",
+			body:    `{"items":[`,
+			message: "invalid JSON: unexpected end of input at line 1, column 10",
+			line:    1,
+			column:  10,
+		},
+		{
+			name:    "Empty",
+			body:    " \n",
+			message: "empty body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the body is streamed, positions must not depend on how much of it was read
+			for _, r := range []io.Reader{strings.NewReader(tt.body), iotest.OneByteReader(strings.NewReader(tt.body))} {
+				_, err := ReadJSON[order](r)
+
+				var decodeErr *JSONDecodeError
+				require.ErrorAs(t, err, &decodeErr)
+				require.Equal(t, tt.message, err.Error())
+				require.Equal(t, tt.field, decodeErr.Field)
+				require.Equal(t, tt.line, decodeErr.Line)
+				require.Equal(t, tt.column, decodeErr.Column)
+			}
+		})
+	}
+
+	// errors of the reader are not JSON errors
+	errRead := errors.New("connection reset")
+	_, err := ReadJSON[order](io.MultiReader(strings.NewReader(`{"items":`), iotest.ErrReader(errRead)))
+	require.ErrorIs(t, err, errRead)
+	require.NotErrorAs(t, err, new(*JSONDecodeError))
+
+	_, err = ReadJSON[order](strings.NewReader(`{"items":[]} {}`))
+	require.ErrorIs(t, err, ErrTrailingData)
+
+	_, err = ReadJSON[order](strings.NewReader("{\"items\":[]}\n\t "))
+	require.NoError(t, err)
+}
+
+func TestGetBody_DecodeErrors(t *testing.T) {
+	t.Parallel()
+
+	serve := func(accept, body string, problems bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+
+		handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = GetBody[order](w, r)
+		}))
+		if problems {
+			handler = ProblemDetails(nil)(handler)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	rr := serve("application/json", `{"items":[{"price":"free"}]}`, false)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.JSONEq(t, `{
+		"message": "field \"items.0.price\" must be a number, got string at line 1, column 25",
+		"field": "items.0.price",
+		"offset": 24,
+		"line": 1,
+		"column": 25
+	}`, rr.Body.String())
+
+	rr = serve("text/plain", `{"customer":{"email":"b"}}`, false)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+	require.Equal(t, `unknown field "email"`, rr.Body.String())
+
+	rr = serve("application/json", `{} {}`, true)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "unexpected data after the JSON value at line 1, column 4",
+		"instance": "/orders",
+		"offset": 3,
+		"line": 1,
+		"column": 4
+	}`, rr.Body.String())
+
+	// bodies over the limit of http.MaxBytesReader
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"items":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr = httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(rr, io.NopCloser(req.Body), 4)
+
+	_, ok := GetBody[order](rr, req)
+	require.False(t, ok)
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
@@ -0,0 +1,209 @@
+package httputils
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Negotiator picks a ResponseEncoder based on the Accept header of a request
+	Negotiator struct {
+		encoders []mediaEncoder
+	}
+
+	mediaEncoder struct {
+		encoder   ResponseEncoder
+		mediaType string
+	}
+
+	// XML encodes the response with encoding/xml. It is not offered by default since maps and
+	// []any cannot be marshalled, register it for handlers whose responses are XML friendly.
+	XML struct{}
+
+	// notAcceptable answers 406 whatever is written, Response.Negotiate uses it when nothing matches
+	notAcceptable struct {
+		offers []string
+	}
+)
+
+var defaultNegotiator = NewNegotiator()
+
+// NewNegotiator offers application/json and text/plain, in this order of preference.
+// Add application/xml with Register("application/xml", XML{}) when every response can be marshalled to XML.
+func NewNegotiator() *Negotiator {
+	return (&Negotiator{}).
+		Register("application/json", JSON{}).
+		Register("text/plain", Text{})
+}
+
+// Register offers mediaType, e.g. application/msgpack or application/cbor. Types registered earlier are preferred
+// when the client accepts several equally, registering an existing type replaces its encoder.
+func (n *Negotiator) Register(mediaType string, encoder ResponseEncoder) *Negotiator {
+	mediaType = strings.ToLower(mediaType)
+
+	idx := slices.IndexFunc(n.encoders, func(e mediaEncoder) bool { return e.mediaType == mediaType })
+	if idx >= 0 {
+		n.encoders[idx].encoder = encoder
+	} else {
+		n.encoders = append(n.encoders, mediaEncoder{mediaType: mediaType, encoder: encoder})
+	}
+
+	return n
+}
+
+// Negotiate returns the encoder and media type best matching the Accept header values.
+// Ranges are weighed by their q-value, then by specificity (type/subtype over type/* over */*),
+// then by registration order. Without an Accept header the first registered encoder is used.
+// ok is false when nothing is acceptable.
+func (n *Negotiator) Negotiate(accept []string) (ResponseEncoder, string, bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 && len(n.encoders) > 0 {
+		return n.encoders[0].encoder, n.encoders[0].mediaType, true
+	}
+
+	best, bestQ, bestSpecificity := -1, 0.0, -1
+
+	for i, offer := range n.encoders {
+		q, specificity := matchAccept(ranges, offer.mediaType)
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = i, q, specificity
+		}
+	}
+
+	if best < 0 {
+		return nil, "", false
+	}
+
+	return n.encoders[best].encoder, n.encoders[best].mediaType, true
+}
+
+// MediaTypes lists the offered media types in order of preference
+func (n *Negotiator) MediaTypes() []string {
+	types := make([]string, len(n.encoders))
+	for i, e := range n.encoders {
+		types[i] = e.mediaType
+	}
+
+	return types
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(values []string) []acceptRange {
+	var ranges []acceptRange
+
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			mediaRange, params, _ := strings.Cut(part, ";")
+
+			typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaRange)), "/")
+			if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+				continue
+			}
+
+			r := acceptRange{typ: typ, subtype: subtype, q: 1}
+
+			for param := range strings.SplitSeq(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "q") {
+					if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+						r.q = q
+					}
+				}
+			}
+
+			ranges = append(ranges, r)
+		}
+	}
+
+	return ranges
+}
+
+// matchAccept returns the q-value of the most specific range matching mediaType, 0 when none does
+func matchAccept(ranges []acceptRange, mediaType string) (float64, int) {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+
+	for _, r := range ranges {
+		var s int
+
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q, specificity
+}
+
+// Negotiate picks the encoder from the Accept header of request, using negotiator or the default one
+// (JSON and text). Vary: Accept is always set. When nothing is acceptable every response helper answers
+// 406 Not Acceptable listing the available media types.
+func (r Response) Negotiate(request *http.Request, negotiator ...*Negotiator) Response {
+	n := defaultNegotiator
+	if len(negotiator) > 0 && negotiator[0] != nil {
+		n = negotiator[0]
+	}
+
+	if !slices.ContainsFunc(r.w.Header().Values("Vary"), func(v string) bool {
+		return slices.ContainsFunc(strings.Split(v, ","), func(field string) bool {
+			return strings.EqualFold(strings.TrimSpace(field), "Accept")
+		})
+	}) {
+		r.w.Header().Add("Vary", "Accept")
+	}
+
+	encoder, _, ok := n.Negotiate(request.Header.Values("Accept"))
+	if !ok {
+		r.encoder = notAcceptable{offers: n.MediaTypes()}
+
+		return r
+	}
+
+	r.encoder = encoder
+
+	return r
+}
+
+func (XML) Encode(w http.ResponseWriter, status int, data ...any) {
+	w.Header().Set("Content-Type", "application/xml")
+
+	if len(data) == 0 {
+		w.WriteHeader(status)
+
+		return
+	}
+
+	bytes, err := xml.Marshal(data[0])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		slog.Error("failed to encode response", "error", err)
+
+		return
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write(append([]byte(xml.Header), bytes...))
+}
+
+func (e notAcceptable) Encode(w http.ResponseWriter, _ int, _ ...any) {
+	Text{}.Encode(w, http.StatusNotAcceptable, "not acceptable, available: "+strings.Join(e.offers, ", "))
+}
@@ -0,0 +1,118 @@
+package httputils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type msgpack struct{}
+
+func (msgpack) Encode(w http.ResponseWriter, status int, data ...any) {
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "msgpack:%v", data)
+}
+
+func TestNegotiator_Negotiate(t *testing.T) {
+	t.Parallel()
+
+	n := NewNegotiator().Register("application/xml", XML{}).Register("application/msgpack", msgpack{})
+
+	tests := []struct {
+		name     string
+		accept   []string
+		expected string
+	}{
+		{name: "NoAccept", accept: nil, expected: "application/json"},
+		{name: "Exact", accept: []string{"text/plain"}, expected: "text/plain"},
+		{name: "CaseInsensitive", accept: []string{"Application/XML"}, expected: "application/xml"},
+		{name: "Any", accept: []string{"*/*"}, expected: "application/json"},
+		{name: "TypeWildcard", accept: []string{"text/*"}, expected: "text/plain"},
+		{name: "QValues", accept: []string{"application/json;q=0.5, application/xml;q=0.9"}, expected: "application/xml"},
+		{name: "SpecificOverWildcard", accept: []string{"*/*", "application/msgpack"}, expected: "application/msgpack"},
+		{name: "MostSpecificRangeWins", accept: []string{"application/*;q=0.1, application/msgpack;q=1, */*;q=0.5"}, expected: "application/msgpack"},
+		{name: "ExcludedWithZero", accept: []string{"application/json;q=0, */*"}, expected: "text/plain"},
+		{name: "Browser", accept: []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, expected: "application/xml"},
+		{name: "InvalidRangesIgnored", accept: []string{"json, */json, text/plain;q=abc"}, expected: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, mediaType, ok := n.Negotiate(tt.accept)
+			require.True(t, ok)
+			require.Equal(t, tt.expected, mediaType)
+		})
+	}
+
+	_, _, ok := n.Negotiate([]string{"image/png, application/json;q=0"})
+	require.False(t, ok)
+	require.Equal(t, []string{"application/json", "text/plain", "application/xml", "application/msgpack"}, n.MediaTypes())
+}
+
+func TestResponse_Negotiate(t *testing.T) {
+	t.Parallel()
+
+	serve := func(accept string, handler func(Response)) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+
+		handler(NewResponse(rr).Negotiate(r))
+
+		return rr
+	}
+
+	rr := serve("", func(res Response) { res.OK(ErrorMessage{Message: "hi"}) })
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.Equal(t, "Accept", rr.Header().Get("Vary"))
+	require.JSONEq(t, `{"message":"hi"}`, rr.Body.String())
+
+	rr = serve("text/plain", func(res Response) { res.NotFoundError() })
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+	require.Equal(t, "requested resource not found", rr.Body.String())
+
+	// XML is opt-in, a browser gets JSON even for values encoding/xml cannot marshal
+	rr = serve("text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", func(res Response) {
+		res.OK(map[string]any{"items": []any{1, "two"}})
+	})
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"items":[1,"two"]}`, rr.Body.String())
+
+	rr = serve("image/png", func(res Response) { res.OK(ErrorMessage{Message: "hi"}) })
+	require.Equal(t, http.StatusNotAcceptable, rr.Code)
+	require.Equal(t, "Accept", rr.Header().Get("Vary"))
+	require.Equal(t, "not acceptable, available: application/json, text/plain", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/xml")
+
+	NewResponse(rr).Negotiate(r, NewNegotiator().Register("application/xml", XML{})).Created(ErrorMessage{Message: "hi"})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.Equal(t, "application/xml", rr.Header().Get("Content-Type"))
+	require.Contains(t, rr.Body.String(), "<ErrorMessage><message>hi</message></ErrorMessage>")
+
+	// a custom negotiator and an existing Vary header
+	rr = httptest.NewRecorder()
+	rr.Header().Set("Vary", "Origin, accept")
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/*")
+
+	NewResponse(rr).Negotiate(r, (&Negotiator{}).Register("application/msgpack", msgpack{})).OK("hi")
+	require.Equal(t, "application/msgpack", rr.Header().Get("Content-Type"))
+	require.Equal(t, []string{"Origin, accept"}, rr.Header().Values("Vary"))
+	require.Equal(t, "msgpack:[hi]", rr.Body.String())
+}
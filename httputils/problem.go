@@ -0,0 +1,238 @@
+package httputils
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object
+type Problem struct {
+	// Extensions are rendered as additional top level members, they cannot replace the standard ones
+	Extensions map[string]any `json:"-"`
+	// Type is a URI identifying the problem type, about:blank when empty
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Status   int    `json:"status,omitempty"`
+}
+
+// ProblemTypes configures problem details responses. Helpers such as NotFoundError use the problem registered
+// for their status, or about:blank with the status text as title when there is none.
+type ProblemTypes struct {
+	byStatus map[int]Problem
+	byType   map[string]Problem
+	mu       sync.RWMutex
+}
+
+// problemWriter carries the problem types of a router to NewResponse
+type problemWriter struct {
+	http.ResponseWriter
+	types    *ProblemTypes
+	instance string
+}
+
+func NewProblemTypes() *ProblemTypes {
+	return &ProblemTypes{
+		byStatus: make(map[int]Problem),
+		byType:   make(map[string]Problem),
+	}
+}
+
+// Register adds a custom problem type, later looked up by its Type with ProblemTypes.New
+func (p *ProblemTypes) Register(problem Problem) *ProblemTypes {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.byType[problem.Type] = problem
+
+	return p
+}
+
+// RegisterStatus makes every error response with status use problem as its template
+func (p *ProblemTypes) RegisterStatus(status int, problem Problem) *ProblemTypes {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	problem.Status = status
+	p.byStatus[status] = problem
+
+	return p
+}
+
+// New returns a copy of the registered problem type with detail set, or an about:blank problem
+// with status 500 when typ is not registered
+func (p *ProblemTypes) New(typ, detail string) Problem {
+	p.mu.RLock()
+	problem, ok := p.byType[typ]
+	p.mu.RUnlock()
+
+	if !ok {
+		problem = p.ForStatus(http.StatusInternalServerError)
+	}
+
+	problem.Detail = detail
+	problem.Extensions = maps.Clone(problem.Extensions)
+
+	return problem
+}
+
+// ForStatus returns the problem registered for status or an about:blank problem titled with the status text
+func (p *ProblemTypes) ForStatus(status int) Problem {
+	if p != nil {
+		p.mu.RLock()
+		problem, ok := p.byStatus[status]
+		p.mu.RUnlock()
+
+		if ok {
+			problem.Extensions = maps.Clone(problem.Extensions)
+
+			return problem
+		}
+	}
+
+	return Problem{Title: http.StatusText(status), Status: status}
+}
+
+// ProblemDetails switches every Response created below it to problem details, types may be nil:
+//
+//	r.Route("/api/v2", func(r chi.Router) {
+//		r.Use(httputils.ProblemDetails(types))
+//	})
+func ProblemDetails(types *ProblemTypes) func(http.Handler) http.Handler {
+	if types == nil {
+		types = NewProblemTypes()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&problemWriter{ResponseWriter: w, types: types, instance: r.URL.Path}, r)
+		})
+	}
+}
+
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *problemWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(w.ResponseWriter, src)
+}
+
+// Flush and Hijack are passed through so streaming and websocket handlers keep working below ProblemDetails
+func (w *problemWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *problemWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// problemWriterOf finds the problemWriter installed by ProblemDetails, other middlewares may have wrapped it since
+func problemWriterOf(w http.ResponseWriter) *problemWriter {
+	for {
+		if pw, ok := w.(*problemWriter); ok {
+			return pw
+		}
+
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+
+		w = unwrapper.Unwrap()
+	}
+}
+
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+
+	return p.Title
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+5)
+	maps.Copy(members, p.Extensions)
+
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+
+	members["status"] = p.Status
+	members["title"] = p.Title
+
+	for key, value := range map[string]string{"detail": p.Detail, "instance": p.Instance} {
+		if value != "" {
+			members[key] = value
+		} else {
+			delete(members, key)
+		}
+	}
+
+	return json.Marshal(members)
+}
+
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	type plain Problem
+
+	var standard plain
+	if err := json.Unmarshal(data, &standard); err != nil {
+		return err
+	}
+
+	*p = Problem(standard)
+
+	for _, key := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(members, key)
+	}
+
+	if len(members) == 0 {
+		return nil
+	}
+
+	p.Extensions = make(map[string]any, len(members))
+	for key, raw := range members {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+
+		p.Extensions[key] = value
+	}
+
+	return nil
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+
+	bytes, err := json.Marshal(problem)
+	if err != nil {
+		slog.Error("failed to encode problem", "error", err)
+
+		problem = Problem{Title: http.StatusText(http.StatusInternalServerError), Status: http.StatusInternalServerError}
+		bytes, _ = json.Marshal(problem)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_, _ = w.Write(bytes)
+}
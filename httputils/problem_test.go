@@ -0,0 +1,204 @@
+package httputils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Oudwins/zog"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProblem_JSON(t *testing.T) {
+	t.Parallel()
+
+	problem := Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Instance:   "/account/12345/msgs/abc",
+		Extensions: map[string]any{"balance": 30, "status": 200},
+	}
+
+	data, err := json.Marshal(problem)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"detail": "Your current balance is 30, but that costs 50.",
+		"instance": "/account/12345/msgs/abc",
+		"balance": 30
+	}`, string(data))
+
+	var decoded Problem
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, problem.Type, decoded.Type)
+	require.Equal(t, problem.Status, decoded.Status)
+	require.Equal(t, map[string]any{"balance": float64(30)}, decoded.Extensions)
+
+	data, err = json.Marshal(Problem{Status: http.StatusNotFound, Title: "Not Found"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404}`, string(data))
+	require.Equal(t, "Not Found", Problem{Title: "Not Found"}.Error())
+}
+
+func TestProblemDetails_Router(t *testing.T) {
+	t.Parallel()
+
+	types := NewProblemTypes().
+		RegisterStatus(http.StatusNotFound, Problem{Type: "https://example.com/probs/not-found", Title: "Resource not found"}).
+		Register(Problem{
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Status:     http.StatusForbidden,
+			Extensions: map[string]any{"docs": "https://example.com/docs/credit"},
+		})
+
+	r := chi.NewRouter()
+	r.Get("/v1/missing", func(w http.ResponseWriter, _ *http.Request) {
+		NewResponse(w).NotFoundError()
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(ProblemDetails(types))
+		// middlewares below may wrap the writer again
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(middleware.NewWrapResponseWriter(w, r.ProtoMajor), r)
+			})
+		})
+		r.Get("/v2/missing", func(w http.ResponseWriter, _ *http.Request) {
+			NewResponse(w).NotFoundError()
+		})
+		r.Get("/v2/conflict", func(w http.ResponseWriter, _ *http.Request) {
+			NewResponse(w).ConflictError()
+		})
+		r.Get("/v2/credit", func(w http.ResponseWriter, _ *http.Request) {
+			NewResponse(w).Problem(types.New("https://example.com/probs/out-of-credit", "balance is 30"))
+		})
+		r.Get("/v2/invalid", func(w http.ResponseWriter, _ *http.Request) {
+			NewResponse(w).ValidationError(zog.ZogIssueMap{"name": {{Message: "is required"}}})
+		})
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return rr
+	}
+
+	rr := serve("/v1/missing")
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"message":"requested resource not found"}`, rr.Body.String())
+
+	rr = serve("/v2/missing")
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{
+		"type": "https://example.com/probs/not-found",
+		"title": "Resource not found",
+		"status": 404,
+		"detail": "requested resource not found",
+		"instance": "/v2/missing"
+	}`, rr.Body.String())
+
+	rr = serve("/v2/conflict")
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Conflict",
+		"status": 409,
+		"detail": "resource already exists",
+		"instance": "/v2/conflict"
+	}`, rr.Body.String())
+
+	rr = serve("/v2/credit")
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.JSONEq(t, `{
+		"type": "https://example.com/probs/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"detail": "balance is 30",
+		"instance": "/v2/credit",
+		"docs": "https://example.com/docs/credit"
+	}`, rr.Body.String())
+
+	rr = serve("/v2/invalid")
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	require.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "validation error",
+		"instance": "/v2/invalid",
+		"errors": {"name": ["is required"]}
+	}`, rr.Body.String())
+}
+
+func TestProblemDetails_PassesThroughWriterInterfaces(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	srv := httptest.NewServer(ProblemDetails(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stream":
+			_, _ = w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush() //nolint:forcetypeassert
+		case "/hijack":
+			conn, buf, err := w.(http.Hijacker).Hijack() //nolint:forcetypeassert
+			if err != nil {
+				NewResponse(w).InternalServerError()
+
+				return
+			}
+
+			defer func() { _ = conn.Close() }()
+
+			_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+			_ = buf.Flush()
+		}
+	})))
+	defer srv.Close()
+
+	for path, expected := range map[string]string{"/stream": "chunk", "/hijack": "hijacked"} {
+		res, err := srv.Client().Get(srv.URL + path) //nolint:noctx
+		req.NoError(err)
+
+		body, err := io.ReadAll(res.Body)
+		req.NoError(err)
+		req.NoError(res.Body.Close())
+		req.Equal(http.StatusOK, res.StatusCode, path)
+		req.Equal(expected, string(body), path)
+	}
+
+	rec := httptest.NewRecorder()
+	(&problemWriter{ResponseWriter: rec}).Flush()
+	req.True(rec.Flushed)
+}
+
+func TestResponse_Problems(t *testing.T) {
+	t.Parallel()
+
+	rr := httptest.NewRecorder()
+	NewResponse(rr).Problems(nil).Unauthorized()
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"unauthorized"}`, rr.Body.String())
+
+	r := SetupRouter(&RouterSetupOptions{Middleware: &MiddlewareConfig{}, Problems: NewProblemTypes()})
+	r.Get("/", func(w http.ResponseWriter, _ *http.Request) {
+		NewResponse(w).ForbiddenError()
+	})
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+}
@@ -2,6 +2,7 @@ package httputils
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -18,15 +19,25 @@ type Response struct {
 	w          http.ResponseWriter
 	encoder    ResponseEncoder
 	controller *http.ResponseController
-	cookies    []*http.Cookie
+	// problems is set in problem details mode, see ProblemDetails
+	problems *ProblemTypes
+	instance string
+	cookies  []*http.Cookie
 }
 
 func NewResponse(w http.ResponseWriter) Response {
-	return Response{
+	res := Response{
 		w:          w,
 		controller: http.NewResponseController(w),
 		encoder:    JSON{},
 	}
+
+	if pw := problemWriterOf(w); pw != nil {
+		res.problems = pw.types
+		res.instance = pw.instance
+	}
+
+	return res
 }
 
 func (r Response) JSON() Response {
@@ -47,6 +58,28 @@ func (r Response) SetEncoder(encoder ResponseEncoder) Response {
 	return r
 }
 
+// Problems renders every error helper as application/problem+json, like ProblemDetails does for a router.
+// types may be nil.
+func (r Response) Problems(types *ProblemTypes) Response {
+	if types == nil {
+		types = NewProblemTypes()
+	}
+
+	r.problems = types
+
+	return r
+}
+
+// Problem writes problem as application/problem+json whatever the mode of the response.
+// Instance defaults to the request path under ProblemDetails.
+func (r Response) Problem(problem Problem) {
+	if problem.Instance == "" {
+		problem.Instance = r.instance
+	}
+
+	writeProblem(r.w, problem)
+}
+
 func (r Response) OK(data ...any) {
 	r.encoder.Encode(r.w, http.StatusOK, data...)
 }
@@ -56,6 +89,14 @@ func (r Response) Created(data ...any) {
 }
 
 func (r Response) Error(status int, err string) {
+	if r.problems != nil {
+		problem := r.problems.ForStatus(status)
+		problem.Detail = err
+		r.Problem(problem)
+
+		return
+	}
+
 	r.encoder.Encode(r.w, status, ErrorMessage{Message: err})
 }
 
@@ -92,6 +133,20 @@ func (r Response) BadRequest() {
 }
 
 func (r Response) ValidationError(err zog.ZogIssueMap) {
+	if r.problems != nil {
+		problem := r.problems.ForStatus(http.StatusUnprocessableEntity)
+		problem.Detail = "validation error"
+
+		if problem.Extensions == nil {
+			problem.Extensions = make(map[string]any, 1)
+		}
+
+		problem.Extensions["errors"] = zog.Issues.SanitizeMapAndCollect(err)
+		r.Problem(problem)
+
+		return
+	}
+
 	r.encoder.Encode(r.w, http.StatusUnprocessableEntity, map[string]any{
 		"message": "validation error",
 		"errors":  zog.Issues.SanitizeMapAndCollect(err),
@@ -196,7 +251,7 @@ func (Text) Encode(w http.ResponseWriter, status int, data ...any) {
 	w.WriteHeader(status)
 
 	if len(data) > 0 {
-		_, _ = w.Write([]byte(data[0].(string))) //nolint:forcetypeassert
+		_, _ = fmt.Fprint(w, data[0])
 
 		return
 	}
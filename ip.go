@@ -7,9 +7,11 @@ import (
 )
 
 const (
-	UnknownIP           = "UNKNOWN IP"
-	HeaderXForwardedFor = "X-Forwarded-For"
-	HeaderXRealIP       = "X-Real-IP"
+	UnknownIP             = "UNKNOWN IP"
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+	HeaderXRealIP         = "X-Real-IP"
 )
 
 var (
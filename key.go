@@ -1,63 +1,231 @@
 package utils
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha3"
 	"crypto/sha512"
+	"encoding/base32"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
+	"log/slog"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/blake2b"
 )
 
 const (
-	Base64Prefix = "base64:"
+	HexPrefix       = "hex:"
+	Base64Prefix    = "base64:"
+	Base64URLPrefix = "base64url:"
+	Base32Prefix    = "base32:"
+	RawPrefix       = "raw:"
+	FilePrefix      = "file:"
 )
 
-var ErrInvalidKey = errors.New("invalid key format")
+// KeyEncoding is the format a key was decoded from
+type KeyEncoding string
 
+const (
+	KeyEncodingHex       KeyEncoding = "hex"
+	KeyEncodingBase64    KeyEncoding = "base64"
+	KeyEncodingBase64URL KeyEncoding = "base64url"
+	KeyEncodingBase32    KeyEncoding = "base32"
+	KeyEncodingRaw       KeyEncoding = "raw"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid key format")
+	ErrNestedKeyFile = errors.New("key file must not reference another file")
+	ErrUnknownHasher = errors.New("unknown hash algorithm")
+)
+
+// ParseKey decodes a key from one of the formats below, hex is used when there is no prefix:
+//
+//	hex:<hex, same as no prefix>
+//	base64:<standard base64>
+//	base64url:<url-safe base64, padding optional>
+//	base32:<standard base32, padding optional>
+//	raw:<the bytes of the string itself>
+//	file:<path to a file holding a key in any of the other formats>
 func ParseKey(key string) ([]byte, error) {
+	k, err := NewKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.bytes, nil
+}
+
+func decodeKey(key string, allowFile bool) ([]byte, KeyEncoding, error) {
 	if key == "" {
+		return nil, "", ErrInvalidKey
+	}
+
+	switch {
+	case strings.HasPrefix(key, HexPrefix):
+		decoded, err := hex.DecodeString(key[len(HexPrefix):])
+
+		return decoded, KeyEncodingHex, err
+	case strings.HasPrefix(key, Base64Prefix):
+		decoded, err := base64.StdEncoding.DecodeString(key[len(Base64Prefix):])
+
+		return decoded, KeyEncodingBase64, err
+	case strings.HasPrefix(key, Base64URLPrefix):
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key[len(Base64URLPrefix):], "="))
+
+		return decoded, KeyEncodingBase64URL, err
+	case strings.HasPrefix(key, Base32Prefix):
+		decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).
+			DecodeString(strings.TrimRight(key[len(Base32Prefix):], "="))
+
+		return decoded, KeyEncodingBase32, err
+	case strings.HasPrefix(key, RawPrefix):
+		if len(key) == len(RawPrefix) {
+			return nil, "", ErrInvalidKey
+		}
+
+		return []byte(key[len(RawPrefix):]), KeyEncodingRaw, nil
+	case strings.HasPrefix(key, FilePrefix):
+		if !allowFile {
+			return nil, "", ErrNestedKeyFile
+		}
+
+		path := key[len(FilePrefix):]
+
+		contents, err := ReadSecretFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading key file %s: %w", path, err)
+		}
+
+		return decodeKey(contents, false)
+	default:
+		decoded, err := hex.DecodeString(key)
+
+		return decoded, KeyEncodingHex, err
+	}
+}
+
+// GenerateKey returns n random bytes in the base64: format accepted by ParseKey
+//
+//	key, _ := utils.GenerateKey(32)
+//	fmt.Println("APP_KEY=" + key)
+func GenerateKey(n int) (string, error) {
+	if n <= 0 {
+		return "", ErrInvalidKey
+	}
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	defer clear(b)
+
+	return Base64Prefix + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Key is decoded key material that never prints itself.
+// String, GoString and LogValue only show the ID, length and encoding, Close wipes the bytes.
+type Key struct {
+	bytes    []byte
+	id       string
+	encoding KeyEncoding
+}
+
+// NewKey decodes value like ParseKey
+func NewKey(value string) (*Key, error) {
+	decoded, encoding, err := decodeKey(value, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decoded) == 0 {
 		return nil, ErrInvalidKey
 	}
 
-	// Check if the key has a base64 prefix
-	if after, ok := strings.CutPrefix(key, Base64Prefix); ok {
-		// Remove the prefix and decode using base64
-		rawKey := after
+	return NewKeyFromBytes(decoded, encoding), nil
+}
+
+// NewKeyFromBytes wraps b without copying it, Close will wipe b
+func NewKeyFromBytes(b []byte, encoding KeyEncoding) *Key {
+	sum := sha256.Sum256(b)
 
-		return base64.StdEncoding.DecodeString(rawKey)
+	return &Key{
+		bytes:    b,
+		encoding: encoding,
+		id:       hex.EncodeToString(sum[:8]),
 	}
+}
+
+// Bytes returns the key material, it must not be used after Close
+func (k *Key) Bytes() []byte {
+	return k.bytes
+}
+
+func (k *Key) Len() int {
+	return len(k.bytes)
+}
 
-	// If no prefix, decode using hex
-	return hex.DecodeString(key)
+func (k *Key) Encoding() KeyEncoding {
+	return k.encoding
 }
 
-func ParseHasher(algo string) func() hash.Hash {
+// ID is a stable identifier derived from a SHA-256 of the key, safe to log and compare
+func (k *Key) ID() string {
+	return k.id
+}
+
+// Close overwrites the key material with zeros
+func (k *Key) Close() error {
+	clear(k.bytes)
+	k.bytes = nil
+
+	return nil
+}
+
+func (k *Key) String() string {
+	return "Key(id=" + k.id + ", len=" + strconv.Itoa(len(k.bytes)) + ", encoding=" + string(k.encoding) + ")"
+}
+
+func (k *Key) GoString() string {
+	return k.String()
+}
+
+func (k *Key) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", k.id),
+		slog.Int("len", len(k.bytes)),
+		slog.String("encoding", string(k.encoding)),
+	)
+}
+
+// ParseHasher returns the hash constructor for algo: sha256, sha512/256, sha3-256, sha3-512 or blake2b (256 bit)
+func ParseHasher(algo string) (func() hash.Hash, error) {
 	switch algo {
 	case "sha256":
-		return sha256.New
+		return sha256.New, nil
 	case "sha512/256":
-		return sha512.New512_256
+		return sha512.New512_256, nil
 	case "sha3-256":
 		return func() hash.Hash {
 			return sha3.New256()
-		}
+		}, nil
 	case "sha3-512":
 		return func() hash.Hash {
 			return sha3.New512()
-		}
+		}, nil
 	case "blake2b":
 		return func() hash.Hash {
 			hasher, _ := blake2b.New256(nil)
 
 			return hasher
-		}
+		}, nil
 	default:
-		panic(fmt.Sprintf("hasher is invalid: check your config: %v", algo))
+		return nil, fmt.Errorf("%w: %q", ErrUnknownHasher, algo)
 	}
 }
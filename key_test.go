@@ -3,6 +3,11 @@ package utils_test
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/CodeLieutenant/utils"
@@ -42,6 +47,41 @@ func TestParseKey(t *testing.T) {
 			expected:    nil,
 			expectError: true,
 		},
+		{
+			name:     "Base64url prefixed key without padding",
+			input:    "base64url:-_8",
+			expected: []byte{0xfb, 0xff},
+		},
+		{
+			name:     "Base32 prefixed key",
+			input:    "base32:NBSWY3DP",
+			expected: []byte("hello"),
+		},
+		{
+			name:     "Base32 prefixed key with padding",
+			input:    "base32:NBSWY3DPEE======",
+			expected: []byte("hello!"),
+		},
+		{
+			name:     "Raw prefixed key",
+			input:    "raw:correct horse battery staple",
+			expected: []byte("correct horse battery staple"),
+		},
+		{
+			name:        "Empty raw key",
+			input:       "raw:",
+			expectError: true,
+		},
+		{
+			name:        "Empty base64 key",
+			input:       "base64:",
+			expectError: true,
+		},
+		{
+			name:        "Invalid base32 key",
+			input:       "base32:189",
+			expectError: true,
+		},
 		{
 			name:        "Invalid hex key",
 			input:       "invalid!hex",
@@ -73,3 +113,97 @@ func mustDecode(data []byte, err error) []byte {
 
 	return data
 }
+
+func TestParseKey_File(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "app_key")
+	req.NoError(os.WriteFile(file, []byte("base32:NBSWY3DP\n"), 0o600))
+
+	key, err := utils.ParseKey(utils.FilePrefix + file)
+	req.NoError(err)
+	req.Equal([]byte("hello"), key)
+
+	k, err := utils.NewKey(utils.FilePrefix + file)
+	req.NoError(err)
+	req.Equal(utils.KeyEncodingBase32, k.Encoding())
+
+	nested := filepath.Join(dir, "nested")
+	req.NoError(os.WriteFile(nested, []byte(utils.FilePrefix+file), 0o600))
+
+	_, err = utils.ParseKey(utils.FilePrefix + nested)
+	req.ErrorIs(err, utils.ErrNestedKeyFile)
+
+	_, err = utils.ParseKey(utils.FilePrefix + filepath.Join(dir, "missing"))
+	req.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	k, err := utils.NewKey("base64:HaFPpa+lXse9vfHgvVR56ij79la6qOXk7u6bg/RYqfo=")
+	req.NoError(err)
+	req.Equal(32, k.Len())
+	req.Equal(utils.KeyEncodingBase64, k.Encoding())
+	req.Len(k.ID(), 16)
+
+	same, err := utils.NewKey("1da14fa5afa55ec7bdbdf1e0bd5479ea28fbf656baa8e5e4eeee9b83f458a9fa")
+	req.NoError(err)
+	req.Equal(k.ID(), same.ID())
+	req.Equal(utils.KeyEncodingHex, same.Encoding())
+
+	expected := "Key(id=" + k.ID() + ", len=32, encoding=base64)"
+	req.Equal(expected, k.String())
+
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+		req.Equal(expected, fmt.Sprintf(verb, k))
+	}
+
+	var b strings.Builder
+
+	slog.New(slog.NewTextHandler(&b, nil)).Info("loaded", "key", k)
+	req.Contains(b.String(), "key.id="+k.ID()+" key.len=32 key.encoding=base64")
+	req.NotContains(b.String(), "HaFPpa")
+
+	material := k.Bytes()
+	req.NoError(k.Close())
+	req.Equal(make([]byte, 32), material)
+	req.Equal(k.ID(), same.ID())
+}
+
+func TestGenerateKey(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	generated, err := utils.GenerateKey(32)
+	req.NoError(err)
+	req.True(strings.HasPrefix(generated, utils.Base64Prefix))
+
+	key, err := utils.ParseKey(generated)
+	req.NoError(err)
+	req.Len(key, 32)
+
+	other, err := utils.GenerateKey(32)
+	req.NoError(err)
+	req.NotEqual(generated, other)
+
+	_, err = utils.GenerateKey(0)
+	req.ErrorIs(err, utils.ErrInvalidKey)
+}
+
+func TestParseHasher(t *testing.T) {
+	t.Parallel()
+
+	for algo, size := range map[string]int{"sha256": 32, "sha512/256": 32, "sha3-256": 32, "sha3-512": 64, "blake2b": 32} {
+		hasher, err := utils.ParseHasher(algo)
+		require.NoError(t, err, algo)
+		require.Equal(t, size, hasher().Size(), algo)
+	}
+
+	_, err := utils.ParseHasher("md5")
+	require.ErrorIs(t, err, utils.ErrUnknownHasher)
+	require.ErrorContains(t, err, `"md5"`)
+}
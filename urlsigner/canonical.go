@@ -0,0 +1,141 @@
+package urlsigner
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonical returns the form of u a signature covers, so that proxies rewriting a link in
+// harmless ways do not break it:
+//
+//   - scheme and host are lowercased, the default port of http and https is removed
+//   - escapes of unreserved characters in the path are decoded and the others uppercased, so %7E and ~ match
+//     but %2F and / do not; a trailing slash is removed (an empty path becomes /)
+//   - query keys are sorted, values of a repeated key are sorted, everything is escaped with url.QueryEscape
+//   - the signature parameter, parameters matching ignore, user info and the fragment are left out
+//
+// An ignore pattern ending in * matches every parameter with that prefix, e.g. utm_*.
+// The other parameters added by Sign (expires, kid, nonce, ...) are kept even when a pattern matches them.
+func Canonical(u *url.URL, ignore ...string) string {
+	return canonicalString(u, u.Query(), ignore)
+}
+
+func canonicalString(u *url.URL, query url.Values, ignore []string) string {
+	scheme := strings.ToLower(u.Scheme)
+
+	host := strings.TrimSuffix(strings.ToLower(u.Host), ":")
+	if port, ok := defaultPorts[scheme]; ok && u.Port() == port {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+
+	p := strings.TrimRight(canonicalPath(u.EscapedPath()), "/")
+	if p == "" {
+		p = "/"
+	}
+
+	var b strings.Builder
+
+	if scheme != "" {
+		_, _ = b.WriteString(scheme + ":")
+	}
+
+	if host != "" {
+		_, _ = b.WriteString("//" + host)
+	}
+
+	_, _ = b.WriteString(p)
+
+	if q := canonicalQuery(query, ignore); q != "" {
+		_, _ = b.WriteString("?" + q)
+	}
+
+	return b.String()
+}
+
+// canonicalPath normalizes the escapes of an escaped path as RFC 3986 section 6.2.2 does
+func canonicalPath(escaped string) string {
+	if !strings.Contains(escaped, "%") {
+		return escaped
+	}
+
+	var b strings.Builder
+
+	b.Grow(len(escaped))
+
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '%' || i+2 >= len(escaped) {
+			_ = b.WriteByte(escaped[i])
+
+			continue
+		}
+
+		c, err := strconv.ParseUint(escaped[i+1:i+3], 16, 8)
+		if err != nil {
+			_ = b.WriteByte(escaped[i])
+
+			continue
+		}
+
+		if isUnreserved(byte(c)) {
+			_ = b.WriteByte(byte(c))
+		} else {
+			_, _ = b.WriteString("%" + strings.ToUpper(escaped[i+1:i+3]))
+		}
+
+		i += 2
+	}
+
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func canonicalQuery(query url.Values, ignore []string) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != Signature && (slices.Contains(reserved, key) || !isIgnored(key, ignore)) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	var b strings.Builder
+
+	for _, key := range keys {
+		values := slices.Sorted(slices.Values(query[key]))
+		for _, value := range values {
+			if b.Len() > 0 {
+				_ = b.WriteByte('&')
+			}
+
+			_, _ = b.WriteString(url.QueryEscape(key) + "=" + url.QueryEscape(value))
+		}
+	}
+
+	return b.String()
+}
+
+func isIgnored(key string, ignore []string) bool {
+	for _, pattern := range ignore {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package urlsigner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+// KeyID is the query parameter naming the key a URL was signed with
+const KeyID = "kid"
+
+const (
+	MinKeySize = 32
+	MaxKeySize = 64
+)
+
+var (
+	ErrKeySize   = errors.New("key must be between 32 and 64 bytes")
+	ErrEmptyRing = errors.New("keyring needs at least one key")
+	// ErrUnknownKey is an ErrInvalidSignature naming a key the keyring does not hold
+	ErrUnknownKey = fmt.Errorf("%w: unknown key id", ErrInvalidSignature)
+)
+
+type ringKey struct {
+	id  string
+	key []byte
+}
+
+// Keyring holds the active signing key and retired keys that are still accepted during verification.
+// Key IDs are derived from the key material (see utils.Key.ID), so every instance agrees on them without extra config.
+type Keyring struct {
+	keys []ringKey
+}
+
+// NewKeyring signs with active and keeps accepting URLs signed with any of the retired keys
+func NewKeyring(active []byte, retired ...[]byte) (*Keyring, error) {
+	ring := &Keyring{keys: make([]ringKey, 0, len(retired)+1)}
+
+	for i, key := range append([][]byte{active}, retired...) {
+		if len(key) < MinKeySize || len(key) > MaxKeySize {
+			return nil, fmt.Errorf("%w: key %d has %d bytes", ErrKeySize, i, len(key))
+		}
+
+		ring.keys = append(ring.keys, ringKey{id: utils.NewKeyFromBytes(key, "").ID(), key: key})
+	}
+
+	return ring, nil
+}
+
+// KeyringFromEnv builds a keyring from a comma separated list of keys in any utils.ParseKey format.
+// The first key is active, the rest are retired. To rotate, prepend the new key and drop old ones
+// once every link signed with them has expired:
+//
+//	URL_SIGNING_KEYS=base64:<new>,base64:<previous>
+func KeyringFromEnv(e utils.Env, key string) (*Keyring, error) {
+	keys, exists, err := utils.LookupKeysEnv(e, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s is not set", ErrEmptyRing, key)
+	}
+
+	return NewKeyring(keys[0], keys[1:]...)
+}
+
+// ActiveID is the ID of the key used for signing
+func (r *Keyring) ActiveID() string {
+	return r.keys[0].id
+}
+
+// IDs returns the IDs of every accepted key, active first
+func (r *Keyring) IDs() []string {
+	ids := make([]string, len(r.keys))
+	for i, k := range r.keys {
+		ids[i] = k.id
+	}
+
+	return ids
+}
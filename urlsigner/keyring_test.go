@@ -0,0 +1,145 @@
+package urlsigner
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func newKeyringSigner(tb testing.TB, ring *Keyring, opts ...Options) *HMACSigner {
+	tb.Helper()
+
+	s, err := NewWithKeyring("sha256", ring, opts...)
+	require.NoError(tb, err)
+
+	return s
+}
+
+func Test_Keyring_RotationKeepsRetiredKeysValid(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	oldKey, newKey := randomKey(t), randomKey(t)
+
+	oldRing, err := NewKeyring(oldKey)
+	req.NoError(err)
+
+	before := newKeyringSigner(t, oldRing, Options{Now: clock})
+	signed, err := before.Sign("https://example.com/file?id=1", time.Hour)
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.Equal(oldRing.ActiveID(), u.Query().Get(KeyID))
+
+	rotated, err := NewKeyring(newKey, oldKey)
+	req.NoError(err)
+	req.Equal([]string{rotated.ActiveID(), oldRing.ActiveID()}, rotated.IDs())
+
+	after := newKeyringSigner(t, rotated, Options{Now: clock})
+	req.NoError(after.Verify(u))
+
+	fresh, err := after.Sign("https://example.com/file?id=2", time.Hour)
+	req.NoError(err)
+
+	u, _ = url.Parse(fresh)
+	req.Equal(rotated.ActiveID(), u.Query().Get(KeyID))
+	req.NoError(after.Verify(u))
+
+	// once the old key is dropped its links stop verifying
+	dropped, err := NewKeyring(newKey)
+	req.NoError(err)
+
+	u, _ = url.Parse(signed)
+	err = newKeyringSigner(t, dropped, Options{Now: clock}).Verify(u)
+	req.ErrorIs(err, ErrUnknownKey)
+	req.ErrorIs(err, ErrInvalidSignature)
+}
+
+func Test_Keyring_VerifiesLegacyLinksWithoutKeyID(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	legacyKey := randomKey(t)
+
+	signed, err := newSigner(t, "sha256", legacyKey).Sign("https://example.com/legacy", 0)
+	req.NoError(err)
+
+	ring, err := NewKeyring(randomKey(t), legacyKey)
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.NoError(newKeyringSigner(t, ring).Verify(u))
+
+	other, err := NewKeyring(randomKey(t))
+	req.NoError(err)
+
+	u, _ = url.Parse(signed)
+	req.Equal(ErrInvalidSignature, newKeyringSigner(t, other).Verify(u))
+}
+
+func Test_Keyring_KeyIDIsSigned(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	first, second := randomKey(t), randomKey(t)
+
+	ring, err := NewKeyring(first, second)
+	req.NoError(err)
+
+	s := newKeyringSigner(t, ring)
+	signed, err := s.Sign("https://example.com/a", 0)
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	q := u.Query()
+	q.Set(KeyID, ring.IDs()[1])
+	u.RawQuery = q.Encode()
+	req.Equal(ErrInvalidSignature, s.Verify(u))
+}
+
+func Test_NewKeyring_InvalidKeys(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewKeyring(randomKey(t, 31))
+	require.ErrorIs(t, err, ErrKeySize)
+
+	_, err = NewKeyring(randomKey(t), randomKey(t, 65))
+	require.ErrorIs(t, err, ErrKeySize)
+}
+
+func Test_KeyringFromEnv(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	active, retired := randomKey(t), randomKey(t, 64)
+	env := utils.Env{EnvProvider: utils.NewMapEnvProvider(map[string]string{
+		"URL_SIGNING_KEYS": "base64:" + base64.StdEncoding.EncodeToString(active) + ", base64:" + base64.StdEncoding.EncodeToString(retired),
+		"EMPTY":            " , ",
+		"BROKEN":           "base64:" + base64.StdEncoding.EncodeToString(active) + ",zz",
+	})}
+
+	ring, err := KeyringFromEnv(env, "URL_SIGNING_KEYS")
+	req.NoError(err)
+	req.Equal(utils.NewKeyFromBytes(active, "").ID(), ring.ActiveID())
+	req.Len(ring.IDs(), 2)
+
+	_, err = KeyringFromEnv(env, "EMPTY")
+	req.ErrorIs(err, ErrEmptyRing)
+
+	_, err = KeyringFromEnv(env, "MISSING")
+	req.ErrorIs(err, ErrEmptyRing)
+
+	var parseErr *utils.EnvParseError
+
+	_, err = KeyringFromEnv(env, "BROKEN")
+	req.ErrorAs(err, &parseErr)
+	req.ErrorContains(err, "element 1")
+	req.NotContains(err.Error(), base64.StdEncoding.EncodeToString(active))
+}
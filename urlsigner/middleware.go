@@ -0,0 +1,208 @@
+package urlsigner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CodeLieutenant/utils"
+	"github.com/CodeLieutenant/utils/httputils"
+)
+
+type (
+	// MiddlewareOptions configure Middleware
+	MiddlewareOptions struct {
+		// OnError replaces the default 403/410 response
+		OnError func(w http.ResponseWriter, r *http.Request, err error)
+		// TrustProxy rebuilds the external URL from X-Forwarded-Proto and X-Forwarded-Host and takes the
+		// client IP from X-Forwarded-For or X-Real-IP. Only enable it behind a proxy that sets these headers.
+		TrustProxy bool
+	}
+
+	// Claims describe a verified link, handlers behind Middleware read them with ClaimsFromContext
+	Claims struct {
+		// Expires is zero when the link never expires
+		Expires   time.Time
+		NotBefore time.Time
+		// Fields holds the signed query parameters of the link, without the ones urlsigner adds itself
+		Fields   url.Values
+		KeyID    string
+		ClientIP string
+	}
+
+	claimsKey struct{}
+
+	// claimer is implemented by the signers of this package, it knows which parameters are not signed
+	claimer interface {
+		claims(query url.Values) Claims
+	}
+)
+
+// reserved are the query parameters added by Sign
+var reserved = []string{Signature, Expiration, NotBefore, KeyID, Nonce, Method, Headers, Scope}
+
+// Middleware only lets requests with a valid signed URL through.
+// Invalid links get 403 Forbidden, expired or already used links 410 Gone, both through httputils.Response.
+// The verified Claims are stored in the request context:
+//
+//	r.With(urlsigner.Middleware(signer, urlsigner.MiddlewareOptions{TrustProxy: true})).
+//		Get("/download", func(w http.ResponseWriter, r *http.Request) {
+//			claims, _ := urlsigner.ClaimsFromContext(r.Context())
+//			file := claims.Fields.Get("file")
+//		})
+func Middleware(signer Signer, opts ...MiddlewareOptions) func(http.Handler) http.Handler {
+	var o MiddlewareOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.OnError == nil {
+		o.OnError = writeError
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			external := r
+			if o.TrustProxy {
+				external = forwardedRequest(r)
+			}
+
+			if err := signer.VerifyRequest(external); err != nil {
+				o.OnError(w, r, err)
+
+				return
+			}
+
+			query := external.URL.Query()
+
+			var claims Claims
+			if c, ok := signer.(claimer); ok {
+				claims = c.claims(query)
+			} else {
+				claims = settings{}.claims(query)
+			}
+
+			claims.ClientIP = clientIP(r, o.TrustProxy)
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// ClaimsFromContext returns the claims stored by Middleware
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+
+	return claims, ok
+}
+
+func (c settings) claims(query url.Values) Claims {
+	// Both were checked by Verify, the errors cannot happen
+	expires, _ := parseTime(query.Get(Expiration))
+	notBefore, _ := parseTime(query.Get(NotBefore))
+
+	fields := make(url.Values, len(query))
+	for key, values := range query {
+		if !slices.Contains(reserved, key) && !isIgnored(key, c.ignore) {
+			fields[key] = values
+		}
+	}
+
+	claims := Claims{
+		Fields: fields,
+		KeyID:  query.Get(KeyID),
+	}
+
+	if query.Get(Expiration) != "" {
+		claims.Expires = expires
+	}
+
+	if query.Get(NotBefore) != "" {
+		claims.NotBefore = notBefore
+	}
+
+	return claims
+}
+
+func writeError(w http.ResponseWriter, _ *http.Request, err error) {
+	res := httputils.NewResponse(w)
+
+	switch {
+	case errors.Is(err, ErrExpired):
+		res.Error(http.StatusGone, "link expired")
+	case errors.Is(err, ErrAlreadyUsed):
+		res.Error(http.StatusGone, "link already used")
+	case errors.Is(err, ErrMissingSignature),
+		errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrNotYetValid),
+		errors.Is(err, ErrOutsideScope),
+		errors.Is(err, ErrRequestRequired):
+		res.ForbiddenError()
+	default:
+		// misconfiguration or a failing NonceStore
+		res.InternalServerError()
+	}
+}
+
+// forwardedRequest returns r with the URL the client used in front of the proxy
+func forwardedRequest(r *http.Request) *http.Request {
+	proto := firstValue(r.Header.Get(utils.HeaderXForwardedProto))
+	host := firstValue(r.Header.Get(utils.HeaderXForwardedHost))
+
+	if proto == "" && host == "" {
+		return r
+	}
+
+	u := *r.URL
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+
+	if proto != "" {
+		u.Scheme = proto
+	}
+
+	if host != "" {
+		u.Host = host
+	}
+
+	external := r.WithContext(r.Context())
+	external.URL = &u
+
+	return external
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if ip := utils.RealIP(headerPeeker(r.Header)); len(ip) > 0 {
+			return string(bytes.TrimSpace(ip))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// firstValue takes the first entry of a comma separated header, the one added by the proxy closest to the client
+func firstValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+
+	return strings.TrimSpace(first)
+}
+
+// headerPeeker adapts http.Header to utils.Peekable
+type headerPeeker http.Header
+
+func (h headerPeeker) Peek(key string) []byte {
+	return []byte(http.Header(h).Get(key))
+}
@@ -0,0 +1,114 @@
+package urlsigner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func Test_Middleware(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	clock := now
+	nonces := NewMemoryNonceStore()
+	nonces.now = func() time.Time { return clock }
+
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{
+		Now:          func() time.Time { return clock },
+		IgnoreParams: []string{"utm_*"},
+		Nonces:       nonces,
+	})
+
+	var claims Claims
+
+	handler := Middleware(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		claims, ok = ClaimsFromContext(r.Context())
+		require.True(t, ok)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+		return rec
+	}
+
+	signed, err := s.Sign("http://example.com/download?file=report.pdf", time.Hour)
+	require.NoError(t, err)
+
+	rec := serve(signed + "&utm_source=mail")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, now.Add(time.Hour), claims.Expires)
+	require.Equal(t, url.Values{"file": {"report.pdf"}}, claims.Fields)
+	require.Equal(t, "192.0.2.1", claims.ClientIP)
+
+	rec = serve("http://example.com/download?file=report.pdf")
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.JSONEq(t, `{"message":"forbidden"}`, rec.Body.String())
+
+	once, err := s.Sign("http://example.com/reset", time.Hour, SignOptions{OneTime: true})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, serve(once).Code)
+	require.Equal(t, http.StatusGone, serve(once).Code)
+
+	clock = now.Add(2 * time.Hour)
+	rec = serve(signed)
+	require.Equal(t, http.StatusGone, rec.Code)
+	require.JSONEq(t, `{"message":"link expired"}`, rec.Body.String())
+}
+
+func Test_Middleware_BehindProxy(t *testing.T) {
+	t.Parallel()
+
+	s := newSigner(t, "sha256", randomKey(t, 32))
+
+	signed, err := s.Sign("https://files.example.com/download?file=1", time.Hour)
+	require.NoError(t, err)
+
+	u, _ := url.Parse(signed)
+
+	// the proxy terminates TLS and forwards to the internal address
+	internal := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://10.0.0.5:8080"+u.RequestURI(), nil)
+		r.Header.Set(utils.HeaderXForwardedProto, "https")
+		r.Header.Set(utils.HeaderXForwardedHost, "files.example.com, proxy.internal")
+		r.Header.Set(utils.HeaderXForwardedFor, "203.0.113.7, 10.0.0.1")
+
+		return r
+	}
+
+	var claims Claims
+
+	next := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		claims, _ = ClaimsFromContext(r.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	Middleware(s)(next).ServeHTTP(rec, internal())
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	Middleware(s, MiddlewareOptions{TrustProxy: true})(next).ServeHTTP(rec, internal())
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "203.0.113.7", claims.ClientIP)
+	require.Equal(t, "1", claims.Fields.Get("file"))
+
+	var custom error
+
+	rec = httptest.NewRecorder()
+	Middleware(s, MiddlewareOptions{OnError: func(w http.ResponseWriter, _ *http.Request, err error) {
+		custom = err
+		w.WriteHeader(http.StatusUnauthorized)
+	}})(next).ServeHTTP(rec, internal())
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.ErrorIs(t, custom, ErrInvalidSignature)
+}
@@ -0,0 +1,145 @@
+package urlsigner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Nonce is the query parameter carrying the random value of a one-time link
+const Nonce = "nonce"
+
+// nonceSweepInterval bounds how often MemoryNonceStore drops nonces of expired links
+const nonceSweepInterval = time.Minute
+
+var (
+	ErrAlreadyUsed = errors.New("url already used")
+	// ErrNoNonceStore is returned when verifying a one-time link without Options.Nonces,
+	// accepting it would make the link reusable
+	ErrNoNonceStore = errors.New("one-time url needs a nonce store")
+	// ErrOneTimeExpiry is returned when signing a one-time link without a duration,
+	// the nonce would have to be remembered forever
+	ErrOneTimeExpiry = errors.New("one-time url needs an expiration")
+)
+
+type (
+	// NonceStore remembers the nonces of consumed one-time links.
+	// Use must atomically mark nonce as used and return ErrAlreadyUsed when it already was.
+	// The nonce only has to be remembered until expires, after that the link is rejected as expired anyway.
+	NonceStore interface {
+		Use(ctx context.Context, nonce string, expires time.Time) error
+	}
+
+	// NonceStoreFunc adapts a function to NonceStore, e.g. to back it with Redis:
+	//
+	//	urlsigner.NonceStoreFunc(func(ctx context.Context, nonce string, expires time.Time) error {
+	//		ok, err := rdb.SetNX(ctx, "urlsigner:"+nonce, 1, time.Until(expires)).Result()
+	//		if err != nil {
+	//			return err
+	//		}
+	//		if !ok {
+	//			return urlsigner.ErrAlreadyUsed
+	//		}
+	//		return nil
+	//	})
+	NonceStoreFunc func(ctx context.Context, nonce string, expires time.Time) error
+
+	// MemoryNonceStore keeps used nonces in memory until their links expire.
+	// It only works when every verifying instance shares it, use an external store otherwise.
+	// Give it the Now clock of the signers it serves, a nonce must not look expired to the store while its link is still valid.
+	MemoryNonceStore struct {
+		now       func() time.Time
+		used      map[string]time.Time
+		nextSweep time.Time
+		mu        sync.Mutex
+	}
+)
+
+func (f NonceStoreFunc) Use(ctx context.Context, nonce string, expires time.Time) error {
+	return f(ctx, nonce, expires)
+}
+
+// MemoryNonceStoreOptions configure a MemoryNonceStore
+type MemoryNonceStoreOptions struct {
+	// Now returns the current time, time.Now by default. Use the Options.Now of the signers sharing the store.
+	Now func() time.Time
+}
+
+// NewMemoryNonceStore creates an empty store, nonces of expired links are dropped as new ones are used
+func NewMemoryNonceStore(opts ...MemoryNonceStoreOptions) *MemoryNonceStore {
+	var o MemoryNonceStoreOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+
+	return &MemoryNonceStore{
+		now:  o.Now,
+		used: make(map[string]time.Time),
+	}
+}
+
+func (s *MemoryNonceStore) Use(_ context.Context, nonce string, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	if now.After(s.nextSweep) {
+		for n, exp := range s.used {
+			if now.After(exp) {
+				delete(s.used, n)
+			}
+		}
+
+		s.nextSweep = now.Add(nonceSweepInterval)
+	}
+
+	if _, ok := s.used[nonce]; ok {
+		return ErrAlreadyUsed
+	}
+
+	s.used[nonce] = expires
+
+	return nil
+}
+
+// Len returns the number of remembered nonces
+func (s *MemoryNonceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.used)
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// useNonce consumes the nonce of a one-time link, links without one are left alone
+func useNonce(ctx context.Context, cfg settings, nonce string, expires time.Time) error {
+	if nonce == "" {
+		return nil
+	}
+
+	if cfg.nonces == nil {
+		return ErrNoNonceStore
+	}
+
+	if expires.IsZero() {
+		return ErrOneTimeExpiry
+	}
+
+	return cfg.nonces.Use(ctx, nonce, expires.Add(cfg.skew))
+}
@@ -0,0 +1,162 @@
+package urlsigner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OneTime_ConsumedOnce(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	key := randomKey(t, 32)
+	store := NewMemoryNonceStore()
+	s := newSigner(t, "sha256", key, Options{Nonces: store})
+
+	_, err := s.Sign("https://example.com/reset", 0, SignOptions{OneTime: true})
+	req.ErrorIs(err, ErrOneTimeExpiry)
+
+	signed, err := s.Sign("https://example.com/reset?user=1", time.Hour, SignOptions{OneTime: true})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.NotEmpty(u.Query().Get(Nonce))
+
+	// a verifier without a store must not accept the link
+	req.Equal(ErrNoNonceStore, newSigner(t, "sha256", key).Verify(u))
+
+	req.NoError(s.VerifyRequest(httptest.NewRequest(http.MethodGet, signed, nil)))
+	req.Equal(ErrAlreadyUsed, s.Verify(u))
+
+	// the nonce is signed, swapping it breaks the signature
+	q := u.Query()
+	q.Set(Nonce, "fresh")
+	u.RawQuery = q.Encode()
+	req.Equal(ErrInvalidSignature, s.Verify(u))
+
+	// links without a nonce are unaffected by the store
+	plain, err := s.Sign("https://example.com/page", time.Hour)
+	req.NoError(err)
+
+	u, _ = url.Parse(plain)
+	req.NoError(s.Verify(u))
+	req.NoError(s.Verify(u))
+}
+
+func Test_OneTime_ConcurrentVerify(t *testing.T) {
+	t.Parallel()
+
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Nonces: NewMemoryNonceStore()})
+
+	signed, err := s.Sign("https://example.com/verify-email", time.Hour, SignOptions{OneTime: true})
+	require.NoError(t, err)
+
+	u, _ := url.Parse(signed)
+
+	var (
+		wg       sync.WaitGroup
+		accepted atomic.Int32
+	)
+
+	for range 16 {
+		wg.Go(func() {
+			if s.Verify(u) == nil {
+				accepted.Add(1)
+			}
+		})
+	}
+
+	wg.Wait()
+	require.Equal(t, int32(1), accepted.Load())
+}
+
+func Test_MemoryNonceStore_ForgetsExpired(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{Now: func() time.Time { return now }})
+
+	ctx := context.Background()
+	req.NoError(store.Use(ctx, "a", now.Add(time.Minute)))
+	req.NoError(store.Use(ctx, "b", now.Add(time.Hour)))
+	req.Equal(ErrAlreadyUsed, store.Use(ctx, "a", now.Add(time.Minute)))
+
+	now = now.Add(2 * time.Minute)
+	req.NoError(store.Use(ctx, "c", now.Add(time.Hour)))
+	req.Equal(2, store.Len())
+	req.Equal(ErrAlreadyUsed, store.Use(ctx, "b", now.Add(time.Hour)))
+}
+
+func Test_MemoryNonceStore_NoReplayBeforeSweep(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{Now: func() time.Time { return now }})
+
+	ctx := context.Background()
+	req.NoError(store.Use(ctx, "a", now.Add(time.Second)))
+
+	// past its expiry but before the next sweep the nonce is still remembered
+	now = now.Add(2 * time.Second)
+	req.Equal(ErrAlreadyUsed, store.Use(ctx, "a", now.Add(time.Second)))
+}
+
+func Test_OneTime_StoreSharesSignerClock(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	// a signer clock far behind time.Now would make the links look expired to a store on its own clock
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := NewMemoryNonceStore(MemoryNonceStoreOptions{Now: clock})
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: clock, Nonces: store})
+
+	// building another signer around the store leaves its clock alone
+	_ = newSigner(t, "sha256", randomKey(t, 32), Options{Nonces: store})
+
+	for i := range 2 {
+		signed, err := s.Sign("https://example.com/reset?user="+strconv.Itoa(i), time.Hour, SignOptions{OneTime: true})
+		req.NoError(err)
+
+		u, _ := url.Parse(signed)
+		req.NoError(s.Verify(u))
+		req.Equal(ErrAlreadyUsed, s.Verify(u))
+
+		// sweeps run on the signer clock too
+		now = now.Add(2 * nonceSweepInterval)
+	}
+
+	req.Equal(2, store.Len())
+}
+
+func Test_NonceStoreFunc(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+
+	store := NonceStoreFunc(func(_ context.Context, nonce string, _ time.Time) error {
+		seen = append(seen, nonce)
+
+		return ErrAlreadyUsed
+	})
+
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Nonces: store})
+
+	signed, err := s.Sign("https://example.com/once", time.Hour, SignOptions{OneTime: true})
+	require.NoError(t, err)
+
+	u, _ := url.Parse(signed)
+	require.Equal(t, ErrAlreadyUsed, s.Verify(u))
+	require.Equal(t, []string{u.Query().Get(Nonce)}, seen)
+}
@@ -0,0 +1,247 @@
+package urlsigner
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+var (
+	ErrVerifyOnly         = errors.New("signer holds only a public key")
+	ErrUnsupportedKey     = errors.New("unsupported key, expected Ed25519 or ECDSA P-256")
+	ErrInvalidKeyEncoding = errors.New("invalid key encoding")
+)
+
+// PublicKeySigner signs URLs with an Ed25519 or ECDSA P-256 private key, verification only needs the public key.
+// A central service mints links with NewPublicKeySigner while edge services check them with NewPublicKeyVerifier:
+//
+//	// minting service
+//	private, _ := urlsigner.ParsePrivateKey(os.Getenv("URL_SIGNING_PRIVATE_KEY"))
+//	signer, _ := urlsigner.NewPublicKeySigner(private)
+//
+//	// edge service
+//	public, _ := urlsigner.ParsePublicKey("file:/etc/edge/url-signing.pub")
+//	verifier, _ := urlsigner.NewPublicKeyVerifier(public)
+//
+// The key ID embedded as kid is derived from the public key, so both sides agree on it.
+type PublicKeySigner struct {
+	settings
+	private crypto.Signer
+	public  crypto.PublicKey
+	id      string
+}
+
+// NewPublicKeySigner signs and verifies with private, an ed25519.PrivateKey or a P-256 *ecdsa.PrivateKey
+func NewPublicKeySigner(private crypto.Signer, opts ...Options) (*PublicKeySigner, error) {
+	s, err := NewPublicKeyVerifier(private.Public(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.private = private
+
+	return s, nil
+}
+
+// NewPublicKeyVerifier only verifies, Sign returns ErrVerifyOnly
+func NewPublicKeyVerifier(public crypto.PublicKey, opts ...Options) (*PublicKeySigner, error) {
+	var material []byte
+
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		material = pub
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedKey
+		}
+
+		var err error
+		if material, err = pub.Bytes(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKeySigner{
+		settings: s,
+		public:   public,
+		id:       utils.NewKeyFromBytes(material, "").ID(),
+	}, nil
+}
+
+func (s *PublicKeySigner) Sign(urlString string, duration time.Duration, opts ...SignOptions) (string, error) {
+	return signURL(s, s.settings, urlString, duration, opts)
+}
+
+func (s *PublicKeySigner) Verify(u *url.URL) error {
+	return verifyURL(s, s.settings, u, nil)
+}
+
+func (s *PublicKeySigner) VerifyRequest(r *http.Request) error {
+	return verifyURL(s, s.settings, requestURL(r), r)
+}
+
+// KeyID is derived from the public key and embedded in signed URLs as kid
+func (s *PublicKeySigner) KeyID() string {
+	return s.id
+}
+
+func (s *PublicKeySigner) keyID() string {
+	return s.id
+}
+
+func (s *PublicKeySigner) sign(data string) ([]byte, error) {
+	if s.private == nil {
+		return nil, ErrVerifyOnly
+	}
+
+	if private, ok := s.private.(ed25519.PrivateKey); ok {
+		return ed25519.Sign(private, []byte(data)), nil
+	}
+
+	digest := sha256.Sum256([]byte(data))
+
+	return s.private.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s *PublicKeySigner) verify(data, kid string, sig []byte) error {
+	if kid != "" && kid != s.id {
+		return ErrUnknownKey
+	}
+
+	var valid bool
+
+	switch pub := s.public.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(pub, []byte(data), sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256([]byte(data))
+		valid = ecdsa.VerifyASN1(pub, digest[:], sig)
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ParsePrivateKey loads an Ed25519 or ECDSA P-256 private key from PEM (PKCS #8, or SEC 1 "EC PRIVATE KEY")
+// or from any utils.ParseKey format holding a raw Ed25519 seed (32 bytes) or private key (64 bytes).
+// A file: prefix may point to either.
+func ParsePrivateKey(value string) (crypto.Signer, error) {
+	value, err := readKeyFile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		return parsePEMPrivateKey(block)
+	}
+
+	raw, err := utils.ParseKey(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("%w: raw private keys must be an Ed25519 seed or key, got %d bytes", ErrInvalidKeyEncoding, len(raw))
+	}
+}
+
+// ParsePublicKey loads an Ed25519 or ECDSA P-256 public key from PEM (PKIX "PUBLIC KEY")
+// or from any utils.ParseKey format holding a raw Ed25519 key (32 bytes) or an uncompressed P-256 point (65 bytes).
+// A file: prefix may point to either.
+func ParsePublicKey(value string) (crypto.PublicKey, error) {
+	value, err := readKeyFile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKeyEncoding, block.Type)
+		}
+
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	}
+
+	raw, err := utils.ParseKey(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(raw) {
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(raw), nil
+	case 65:
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+	default:
+		return nil, fmt.Errorf("%w: raw public keys must be Ed25519 or uncompressed P-256, got %d bytes", ErrInvalidKeyEncoding, len(raw))
+	}
+}
+
+func parsePEMPrivateKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, ErrUnsupportedKey
+		}
+
+		return signer, nil
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block %q", ErrInvalidKeyEncoding, block.Type)
+	}
+}
+
+// readKeyFile resolves a file: reference, PEM has to be read here since utils.ParseKey cannot decode it.
+// The file is checked like utils.ParseKey does, see utils.ReadSecretFile.
+func readKeyFile(value string) (string, error) {
+	path, ok := strings.CutPrefix(value, utils.FilePrefix)
+	if !ok {
+		return value, nil
+	}
+
+	contents, err := utils.ReadSecretFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading key file %s: %w", path, err)
+	}
+
+	if strings.HasPrefix(contents, utils.FilePrefix) {
+		return "", utils.ErrNestedKeyFile
+	}
+
+	return contents, nil
+}
@@ -0,0 +1,196 @@
+package urlsigner
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
+)
+
+func pemEncode(tb testing.TB, typ string, der []byte, err error) string {
+	tb.Helper()
+
+	require.NoError(tb, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func Test_PublicKeySigner_SignAndVerify(t *testing.T) {
+	t.Parallel()
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	for name, private := range map[string]crypto.Signer{"ed25519": edPrivate, "ecdsa": ecPrivate} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := require.New(t)
+
+			now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+			clock := func() time.Time { return now }
+
+			signer, err := NewPublicKeySigner(private, Options{Now: clock})
+			req.NoError(err)
+
+			verifier, err := NewPublicKeyVerifier(private.Public(), Options{Now: clock})
+			req.NoError(err)
+			req.Equal(signer.KeyID(), verifier.KeyID())
+
+			signed, err := signer.Sign("https://example.com/file?id=1", time.Hour)
+			req.NoError(err)
+
+			u, _ := url.Parse(signed)
+			req.Equal(signer.KeyID(), u.Query().Get(KeyID))
+			req.NoError(verifier.Verify(u))
+
+			_, err = verifier.Sign("https://example.com/file?id=1", time.Hour)
+			req.ErrorIs(err, ErrVerifyOnly)
+
+			u, _ = url.Parse(signed)
+			q := u.Query()
+			q.Set("id", "2")
+			u.RawQuery = q.Encode()
+			req.Equal(ErrInvalidSignature, verifier.Verify(u))
+
+			u, _ = url.Parse(signed)
+			expired, err := NewPublicKeyVerifier(private.Public(), Options{Now: func() time.Time { return now.Add(2 * time.Hour) }})
+			req.NoError(err)
+			req.Equal(ErrExpired, expired.Verify(u))
+		})
+	}
+}
+
+func Test_PublicKeySigner_OtherKeyRejected(t *testing.T) {
+	t.Parallel()
+
+	_, first, _ := ed25519.GenerateKey(rand.Reader)
+	second, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	signer, err := NewPublicKeySigner(first)
+	require.NoError(t, err)
+
+	verifier, err := NewPublicKeyVerifier(second)
+	require.NoError(t, err)
+
+	signed, err := signer.Sign("https://example.com/a", 0)
+	require.NoError(t, err)
+
+	u, _ := url.Parse(signed)
+	require.ErrorIs(t, verifier.Verify(u), ErrUnknownKey)
+
+	// without the kid the signature itself has to fail
+	u, _ = url.Parse(signed)
+	q := u.Query()
+	q.Del(KeyID)
+	u.RawQuery = q.Encode()
+	require.Equal(t, ErrInvalidSignature, verifier.Verify(u))
+}
+
+func Test_NewPublicKeyVerifier_UnsupportedKeys(t *testing.T) {
+	t.Parallel()
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = NewPublicKeySigner(p384)
+	require.ErrorIs(t, err, ErrUnsupportedKey)
+
+	_, err = NewPublicKeyVerifier([]byte("not a key"))
+	require.ErrorIs(t, err, ErrUnsupportedKey)
+}
+
+func Test_ParseKeys_Formats(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	dir := t.TempDir()
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	req.NoError(err)
+
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	req.NoError(err)
+
+	ecPoint, err := ecPrivate.PublicKey.Bytes()
+	req.NoError(err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	edPEM := pemEncode(t, "PRIVATE KEY", pkcs8, err)
+
+	sec1, err := x509.MarshalECPrivateKey(ecPrivate)
+	ecPEM := pemEncode(t, "EC PRIVATE KEY", sec1, err)
+
+	pkix, err := x509.MarshalPKIXPublicKey(&ecPrivate.PublicKey)
+	ecPublicPEM := pemEncode(t, "PUBLIC KEY", pkix, err)
+
+	pemFile := filepath.Join(dir, "ec.pem")
+	req.NoError(os.WriteFile(pemFile, []byte(ecPEM), 0o600))
+
+	for _, value := range []string{
+		"base64:" + base64.StdEncoding.EncodeToString(edPrivate.Seed()),
+		"base64url:" + base64.RawURLEncoding.EncodeToString(edPrivate),
+		edPEM,
+	} {
+		private, err := ParsePrivateKey(value)
+		req.NoError(err)
+		req.True(edPrivate.Equal(private))
+	}
+
+	for _, value := range []string{ecPEM, utils.FilePrefix + pemFile} {
+		private, err := ParsePrivateKey(value)
+		req.NoError(err)
+		req.True(ecPrivate.Equal(private))
+	}
+
+	public, err := ParsePublicKey("base64:" + base64.StdEncoding.EncodeToString(edPublic))
+	req.NoError(err)
+	req.True(edPublic.Equal(public))
+
+	for _, value := range []string{ecPublicPEM, "base64:" + base64.StdEncoding.EncodeToString(ecPoint)} {
+		public, err := ParsePublicKey(value)
+		req.NoError(err)
+		req.True(ecPrivate.PublicKey.Equal(public))
+	}
+
+	_, err = ParsePrivateKey("base64:" + base64.StdEncoding.EncodeToString([]byte("short")))
+	req.ErrorIs(err, ErrInvalidKeyEncoding)
+
+	_, err = ParsePublicKey(ecPEM)
+	req.ErrorIs(err, ErrInvalidKeyEncoding)
+
+	_, err = ParsePrivateKey(ecPublicPEM)
+	req.ErrorIs(err, ErrInvalidKeyEncoding)
+
+	_, err = ParsePrivateKey(utils.FilePrefix + filepath.Join(dir, "missing.pem"))
+	req.ErrorIs(err, os.ErrNotExist)
+
+	// key files get the same checks as utils.ParseKey
+	large := filepath.Join(dir, "large.pem")
+	req.NoError(os.WriteFile(large, make([]byte, utils.DefaultSecretFileMaxSize+1), 0o600))
+
+	_, err = ParsePrivateKey(utils.FilePrefix + large)
+	req.ErrorIs(err, utils.ErrSecretFileTooLarge)
+
+	if runtime.GOOS != "windows" {
+		req.NoError(os.Chmod(pemFile, 0o666))
+
+		_, err = ParsePrivateKey(utils.FilePrefix + pemFile)
+		req.ErrorIs(err, utils.ErrSecretFilePermission)
+	}
+}
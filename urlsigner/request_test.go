@@ -0,0 +1,119 @@
+package urlsigner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_VerifyRequest_Method(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	s := newSigner(t, "sha256", []byte("a-very-secret-key-that-is-32-bytes!"))
+
+	signed, err := s.Sign("https://example.com/orders/1", time.Hour, SignOptions{Method: "get"})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.Equal(http.MethodGet, u.Query().Get(Method))
+
+	req.NoError(s.VerifyRequest(httptest.NewRequest(http.MethodGet, signed, nil)))
+	req.Equal(ErrInvalidSignature, s.VerifyRequest(httptest.NewRequest(http.MethodPost, signed, nil)))
+
+	// the method is part of the signature, so removing it from the query does not help
+	q := u.Query()
+	q.Del(Method)
+	u.RawQuery = q.Encode()
+	req.Equal(ErrInvalidSignature, s.VerifyRequest(httptest.NewRequest(http.MethodPost, u.String(), nil)))
+
+	u, _ = url.Parse(signed)
+	req.Equal(ErrRequestRequired, s.Verify(u))
+}
+
+func Test_VerifyRequest_Headers(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	s := newSigner(t, "sha256", []byte("a-very-secret-key-that-is-32-bytes!"))
+
+	signed, err := s.Sign("https://example.com/report", time.Hour, SignOptions{
+		Headers: http.Header{"x-tenant-id": {"acme"}},
+	})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.Equal("x-tenant-id", u.Query().Get(Headers))
+
+	r := httptest.NewRequest(http.MethodGet, signed, nil)
+	r.Header.Set("X-Tenant-Id", "acme")
+	req.NoError(s.VerifyRequest(r))
+
+	r = httptest.NewRequest(http.MethodGet, signed, nil)
+	r.Header.Set("X-Tenant-Id", "other")
+	req.Equal(ErrInvalidSignature, s.VerifyRequest(r))
+
+	req.Equal(ErrInvalidSignature, s.VerifyRequest(httptest.NewRequest(http.MethodGet, signed, nil)))
+}
+
+func Test_VerifyRequest_PathPrefix(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	s := newSigner(t, "sha256", []byte("a-very-secret-key-that-is-32-bytes!"))
+
+	_, err := s.Sign("https://example.com/files/124", time.Hour, SignOptions{PathPrefix: "/files/123/"})
+	req.ErrorIs(err, ErrOutsideScope)
+
+	signed, err := s.Sign("https://example.com/files/123/", time.Hour, SignOptions{PathPrefix: "/files/123/"})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+
+	for _, p := range []string{"/files/123/", "/files/123/a", "/files/123/a/b.txt"} {
+		u.Path = p
+		req.NoError(s.VerifyRequest(httptest.NewRequest(http.MethodGet, u.String(), nil)), p)
+	}
+
+	for _, p := range []string{"/files/124", "/files/1234/a", "/files/123/../124/a"} {
+		u.Path = p
+		req.Equal(ErrOutsideScope, s.VerifyRequest(httptest.NewRequest(http.MethodGet, u.String(), nil)), p)
+	}
+
+	// a scope without method or headers still verifies as a plain URL
+	u.Path = "/files/123/a"
+	plain := *u
+	req.NoError(s.Verify(&plain))
+
+	// the scope itself is signed
+	q := u.Query()
+	q.Set(Scope, "/files/")
+	u.RawQuery = q.Encode()
+	req.Equal(ErrInvalidSignature, s.Verify(u))
+}
+
+func Test_VerifyRequest_Unbound(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	s := newSigner(t, "sha256", []byte("a-very-secret-key-that-is-32-bytes!"))
+
+	signed, err := s.Sign("https://example.com/file?id=1", time.Hour)
+	req.NoError(err)
+
+	req.NoError(s.VerifyRequest(httptest.NewRequest(http.MethodPost, signed, nil)))
+
+	// server side requests only carry the path, the host comes from r.Host
+	r := httptest.NewRequest(http.MethodGet, signed, nil)
+	r.URL.Scheme, r.URL.Host = "", ""
+	r.TLS = nil
+	req.Equal(ErrInvalidSignature, s.VerifyRequest(r))
+
+	u, _ := url.Parse(signed)
+	r.URL.Scheme = u.Scheme
+	req.NoError(s.VerifyRequest(r))
+}
@@ -0,0 +1,115 @@
+package urlsigner
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type ticket struct {
+	File   string `json:"file"`
+	UserID int    `json:"user_id"`
+}
+
+func Test_Token_SignAndVerify(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	key := randomKey(t, 32)
+	s := newSigner(t, "sha3-256", key, Options{Now: func() time.Time { return now }})
+
+	token, err := s.SignToken(ticket{File: "report.pdf", UserID: 42}, time.Hour)
+	req.NoError(err)
+	req.NotContains(token, "=")
+
+	claims, err := Verify[ticket](s, token)
+	req.NoError(err)
+	req.Equal(ticket{File: "report.pdf", UserID: 42}, claims)
+
+	later := newSigner(t, "sha3-256", key, Options{Now: func() time.Time { return now.Add(2 * time.Hour) }})
+	_, err = Verify[ticket](later, token)
+	req.Equal(ErrExpired, err)
+
+	// another algorithm with the same key does not accept it
+	_, err = Verify[ticket](newSigner(t, "sha256", key), token)
+	req.Equal(ErrInvalidSignature, err)
+
+	// claims that do not fit T are reported after the signature checked out
+	_, err = Verify[[]string](s, token)
+	req.ErrorContains(err, "decoding claims")
+}
+
+func Test_Token_Tampered(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	s := newSigner(t, "sha256", randomKey(t, 32))
+
+	token, err := s.SignToken(ticket{File: "a.pdf"}, 0)
+	req.NoError(err)
+
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"data":{"file":"b.pdf"}}`))
+
+	_, err = Verify[ticket](s, forged+"."+sig)
+	req.Equal(ErrInvalidSignature, err)
+
+	for _, malformed := range []string{"", payload, payload + ".***", "***." + sig, base64.RawURLEncoding.EncodeToString([]byte("{")) + "." + sig} {
+		_, err = Verify[ticket](s, malformed)
+		req.ErrorIs(err, ErrMalformedToken, malformed)
+		req.ErrorIs(err, ErrInvalidSignature, malformed)
+	}
+
+	// a URL signature is never accepted as a token signature
+	signed, err := s.Sign("https://example.com/a", 0)
+	req.NoError(err)
+	_, urlSig, _ := strings.Cut(signed, Signature+"=")
+
+	_, err = Verify[ticket](s, payload+"."+urlSig)
+	req.Equal(ErrInvalidSignature, err)
+}
+
+func Test_Token_KeyringAndPublicKey(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	oldKey, newKey := randomKey(t), randomKey(t)
+
+	oldRing, err := NewKeyring(oldKey)
+	req.NoError(err)
+
+	token, err := newKeyringSigner(t, oldRing).SignToken(map[string]string{"list": "news"}, 0)
+	req.NoError(err)
+
+	rotated, err := NewKeyring(newKey, oldKey)
+	req.NoError(err)
+
+	claims, err := Verify[map[string]string](newKeyringSigner(t, rotated), token)
+	req.NoError(err)
+	req.Equal("news", claims["list"])
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	req.NoError(err)
+
+	signer, err := NewPublicKeySigner(private)
+	req.NoError(err)
+
+	verifier, err := NewPublicKeyVerifier(private.Public())
+	req.NoError(err)
+
+	token, err = signer.SignToken(ticket{File: "x"}, time.Minute)
+	req.NoError(err)
+
+	got, err := Verify[ticket](verifier, token)
+	req.NoError(err)
+	req.Equal("x", got.File)
+
+	_, err = verifier.SignToken(ticket{}, 0)
+	req.ErrorIs(err, ErrVerifyOnly)
+}
@@ -1,11 +1,17 @@
 package urlsigner
 
 import (
+	"context"
 	"crypto/hmac"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"hash"
+	"net/http"
+	"net/textproto"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/CodeLieutenant/utils"
@@ -13,18 +19,39 @@ import (
 
 type (
 	Signer interface {
-		Sign(string, time.Duration) (string, error)
+		Sign(string, time.Duration, ...SignOptions) (string, error)
 		Verify(*url.URL) error
+		VerifyRequest(*http.Request) error
+	}
+
+	// Options configure a Signer, the zero value writes RFC 3339 times and tolerates no clock skew
+	Options struct {
+		// Now returns the current time, time.Now().UTC() by default
+		Now func() time.Time
+		// IgnoreParams lists query parameters left out of the signature, see Canonical.
+		// Signer and verifier need the same list, patterns matching a parameter added by Sign are rejected.
+		IgnoreParams []string
+		// ClockSkew is tolerated when checking expires and not_before, for servers whose clocks drift apart
+		ClockSkew time.Duration
+		// UnixExpiry writes expires and not_before as Unix seconds, which keeps links shorter.
+		// Verification accepts both formats.
+		UnixExpiry bool
+		// Nonces records consumed one-time links (SignOptions.OneTime), verifying them fails with ErrNoNonceStore without it
+		Nonces NonceStore
 	}
 
 	HMACSigner struct {
-		now    func() time.Time
+		settings
 		hasher func() hash.Hash
+		// keys maps key IDs to their hashers, empty when the signer was created with a single key
+		keys     map[string]func() hash.Hash
+		activeID string
 	}
 )
 
 const (
 	Expiration = "expires"
+	NotBefore  = "not_before"
 	Signature  = "signature"
 )
 
@@ -32,43 +59,249 @@ var (
 	ErrMissingSignature = errors.New("missing signature")
 	ErrInvalidSignature = errors.New("invalid signature")
 	ErrExpired          = errors.New("url expired")
+	ErrNotYetValid      = errors.New("url not yet valid")
+	ErrReservedParam    = errors.New("ignore pattern matches a reserved parameter")
+	ErrRelativePath     = errors.New("url path must start with /")
+	// ErrMalformedTime is an ErrInvalidSignature for a signed expires or not_before that cannot be parsed,
+	// which only happens when another signer shares the key but writes a different format
+	ErrMalformedTime = fmt.Errorf("%w: malformed time", ErrInvalidSignature)
 )
 
-func New(algo string, keyBytes []byte, now ...func() time.Time) *HMACSigner {
-	if len(keyBytes) > 64 || len(keyBytes) < 32 {
-		panic("key must be greater then 32 and less than 64 bytes")
+// New signs with HMAC using algo (see utils.ParseHasher) and a key of 32 to 64 bytes
+func New(algo string, keyBytes []byte, opts ...Options) (*HMACSigner, error) {
+	if len(keyBytes) > MaxKeySize || len(keyBytes) < MinKeySize {
+		return nil, fmt.Errorf("%w: got %d bytes", ErrKeySize, len(keyBytes))
+	}
+
+	hasher, err := hmacHasher(algo, keyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	n := func() time.Time {
-		return time.Now().UTC()
+	return &HMACSigner{
+		settings: s,
+		hasher:   hasher,
+	}, nil
+}
+
+// NewWithKeyring signs with the active key of ring and adds its ID to the URL as the kid parameter.
+// Verification picks the key named by kid, URLs without one (signed by New) are checked against every key.
+func NewWithKeyring(algo string, ring *Keyring, opts ...Options) (*HMACSigner, error) {
+	s, err := newSettings(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(now) > 0 {
-		n = now[0]
+	keys := make(map[string]func() hash.Hash, len(ring.keys))
+	for _, k := range ring.keys {
+		hasher, err := hmacHasher(algo, k.key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[k.id] = hasher
 	}
 
 	return &HMACSigner{
-		now: n,
-		hasher: func() hash.Hash {
-			return hmac.New(utils.ParseHasher(algo), keyBytes)
-		},
+		settings: s,
+		hasher:   keys[ring.ActiveID()],
+		keys:     keys,
+		activeID: ring.ActiveID(),
+	}, nil
+}
+
+// settings are shared by every Signer implementation
+type settings struct {
+	now    func() time.Time
+	ignore []string
+	nonces NonceStore
+	skew   time.Duration
+	unix   bool
+}
+
+func newSettings(opts []Options) (settings, error) {
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	// Leaving expires, kid, ... out of the signature would let anyone rewrite them
+	for _, pattern := range o.IgnoreParams {
+		for _, key := range reserved {
+			if isIgnored(key, []string{pattern}) {
+				return settings{}, fmt.Errorf("%w: %q matches %s", ErrReservedParam, pattern, key)
+			}
+		}
+	}
+
+	if o.Now == nil {
+		o.Now = func() time.Time {
+			return time.Now().UTC()
+		}
+	}
+
+	return settings{
+		now:    o.Now,
+		ignore: o.IgnoreParams,
+		skew:   o.ClockSkew,
+		nonces: o.Nonces,
+		unix:   o.UnixExpiry,
+	}, nil
+}
+
+func (c settings) formatTime(t time.Time) string {
+	if c.unix {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	return t.Format(time.RFC3339Nano)
+}
+
+func hmacHasher(algo string, key []byte) (func() hash.Hash, error) {
+	hasher, err := utils.ParseHasher(algo)
+	if err != nil {
+		return nil, err
 	}
+
+	return func() hash.Hash {
+		return hmac.New(hasher, key)
+	}, nil
 }
 
-func (s *HMACSigner) Sign(urlString string, duration time.Duration) (string, error) {
+// scheme produces and checks the signature over the encoded URL, shared by HMACSigner and PublicKeySigner
+type scheme interface {
+	// keyID names the signing key in the URL, empty to omit it
+	keyID() string
+	sign(data string) ([]byte, error)
+	verify(data, kid string, sig []byte) error
+}
+
+// Sign signs urlString, valid for duration (0 never expires), optionally bound to the request through opts
+func (s *HMACSigner) Sign(urlString string, duration time.Duration, opts ...SignOptions) (string, error) {
+	return signURL(s, s.settings, urlString, duration, opts)
+}
+
+// Verify checks a signed absolute URL, links bound to a method or headers need VerifyRequest.
+// u is not modified.
+func (s *HMACSigner) Verify(u *url.URL) error {
+	return verifyURL(s, s.settings, u, nil)
+}
+
+// VerifyRequest checks the URL of an incoming request along with the method and headers bound at signing time
+func (s *HMACSigner) VerifyRequest(r *http.Request) error {
+	return verifyURL(s, s.settings, requestURL(r), r)
+}
+
+func (s *HMACSigner) keyID() string {
+	return s.activeID
+}
+
+func (s *HMACSigner) sign(data string) ([]byte, error) {
+	return sumURLString(s.hasher, data), nil
+}
+
+func (s *HMACSigner) verify(data, kid string, sig []byte) error {
+	if len(s.keys) == 0 {
+		if !hmac.Equal(sig, sumURLString(s.hasher, data)) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	}
+
+	if kid != "" {
+		hasher, ok := s.keys[kid]
+		if !ok {
+			return ErrUnknownKey
+		}
+
+		if !hmac.Equal(sig, sumURLString(hasher, data)) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	}
+
+	// Links signed before the keyring was introduced carry no kid
+	for _, hasher := range s.keys {
+		if hmac.Equal(sig, sumURLString(hasher, data)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func sumURLString(hasher func() hash.Hash, str string) []byte {
+	h := hasher()
+	_, _ = h.Write(utils.UnsafeBytes(str))
+
+	return h.Sum(nil)
+}
+
+func signURL(sc scheme, cfg settings, urlString string, duration time.Duration, opts []SignOptions) (string, error) {
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return "", err
 	}
 
+	var o SignOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
 	query := u.Query()
 
+	if err = o.bind(u, query); err != nil {
+		return "", err
+	}
+
 	if duration != 0 {
-		query.Set("expires", s.now().Add(duration).Format(time.RFC3339Nano))
+		query.Set(Expiration, cfg.formatTime(cfg.now().Add(duration)))
+	}
+
+	if !o.NotBefore.IsZero() {
+		query.Set(NotBefore, cfg.formatTime(o.NotBefore))
+	}
+
+	if o.OneTime {
+		if duration == 0 {
+			return "", ErrOneTimeExpiry
+		}
+
+		nonce, err := newNonce()
+		if err != nil {
+			return "", err
+		}
+
+		query.Set(Nonce, nonce)
+	}
+
+	if kid := sc.keyID(); kid != "" {
+		query.Set(KeyID, kid)
+	}
+
+	header := make(http.Header, len(o.Headers))
+	for name, values := range o.Headers {
+		header[textproto.CanonicalMIMEHeaderKey(name)] = values
+	}
+
+	if err = checkPath(u, query); err != nil {
+		return "", err
+	}
+
+	sigBytes, err := sc.sign(signingData(u, query, cfg.ignore, o.Method, header))
+	if err != nil {
+		return "", err
 	}
 
 	u.RawQuery = query.Encode()
-	sigBytes := s.sumURLString(u.String())
+
 	signature := base64.RawURLEncoding.EncodeToString(sigBytes)
 
 	if len(u.RawQuery) > 0 {
@@ -80,14 +313,58 @@ func (s *HMACSigner) Sign(urlString string, duration time.Duration) (string, err
 	return u.String(), nil
 }
 
-func (s *HMACSigner) sumURLString(str string) []byte {
-	h := s.hasher()
-	_, _ = h.Write(utils.UnsafeBytes(str))
+func verifyURL(sc scheme, cfg settings, u *url.URL, r *http.Request) error {
+	query := u.Query()
+	sigBytes, query, err := extractAndDecodeSignature(query)
+	if err != nil {
+		return err
+	}
 
-	return h.Sum(nil)
+	var (
+		method string
+		header http.Header
+		ctx    = context.Background()
+	)
+
+	if r != nil {
+		method, header, ctx = r.Method, r.Header, r.Context()
+	} else if isRequestBound(query) {
+		return ErrRequestRequired
+	}
+
+	if err = checkPath(u, query); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+
+	if scope := query.Get(Scope); scope != "" && !inScope(u.Path, scope) {
+		return ErrOutsideScope
+	}
+
+	if err := sc.verify(signingData(u, query, cfg.ignore, method, header), query.Get(KeyID), sigBytes); err != nil {
+		return err
+	}
+
+	expires, err := verifyTimes(query, cfg)
+	if err != nil {
+		return err
+	}
+
+	return useNonce(ctx, cfg, query.Get(Nonce), expires)
+}
+
+// checkPath rejects relative paths, so canonical URLs always start with a scheme, // or /
+// and can never be mistaken for the data of a token (see tokenDomain)
+func checkPath(u *url.URL, query url.Values) error {
+	for _, p := range []string{u.Path, query.Get(Scope)} {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("%w: %q", ErrRelativePath, p)
+		}
+	}
+
+	return nil
 }
 
-func (s *HMACSigner) extractAndDecodeSignature(query url.Values) ([]byte, url.Values, error) {
+func extractAndDecodeSignature(query url.Values) ([]byte, url.Values, error) {
 	signature := query.Get(Signature)
 	if signature == "" {
 		return nil, query, ErrMissingSignature
@@ -103,38 +380,43 @@ func (s *HMACSigner) extractAndDecodeSignature(query url.Values) ([]byte, url.Va
 	return sigBytes, query, nil
 }
 
-func (s *HMACSigner) verifyMAC(original string, sigBytes []byte) bool {
-	computed := s.sumURLString(original)
+// verifyTimes checks expires and not_before, both are already covered by the signature.
+// It returns the expiration, zero when the link never expires.
+func verifyTimes(query url.Values, cfg settings) (time.Time, error) {
+	var expires time.Time
 
-	return hmac.Equal(sigBytes, computed)
-}
+	now := cfg.now()
 
-func (s *HMACSigner) verifyExpiration(query url.Values) error {
-	if len(query.Get(Expiration)) == 0 {
-		return nil
-	}
-	expires, err := time.Parse(time.RFC3339Nano, query.Get(Expiration))
-	if err != nil {
-		panic("urlsigner: invalid expiration format, this should never happen since we validated the signature, CHECK THE FORMATTING FOR THE TIME")
-	}
-	if expires.Before(s.now()) {
-		return ErrExpired
+	if value := query.Get(Expiration); value != "" {
+		var err error
+		if expires, err = parseTime(value); err != nil {
+			return expires, fmt.Errorf("%w: %s: %w", ErrMalformedTime, Expiration, err)
+		}
+
+		if expires.Add(cfg.skew).Before(now) {
+			return expires, ErrExpired
+		}
 	}
 
-	return nil
-}
+	if value := query.Get(NotBefore); value != "" {
+		notBefore, err := parseTime(value)
+		if err != nil {
+			return expires, fmt.Errorf("%w: %s: %w", ErrMalformedTime, NotBefore, err)
+		}
 
-func (s *HMACSigner) Verify(u *url.URL) error {
-	query := u.Query()
-	sigBytes, query, err := s.extractAndDecodeSignature(query)
-	if err != nil {
-		return err
+		if now.Add(cfg.skew).Before(notBefore) {
+			return expires, ErrNotYetValid
+		}
 	}
 
-	u.RawQuery = query.Encode()
-	if !s.verifyMAC(u.String(), sigBytes) {
-		return ErrInvalidSignature
+	return expires, nil
+}
+
+// parseTime accepts Unix seconds and RFC 3339
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
 	}
 
-	return s.verifyExpiration(query)
+	return time.Parse(time.RFC3339Nano, value)
 }
@@ -4,10 +4,13 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/CodeLieutenant/utils"
 )
 
 func randomKey(tb testing.TB, size ...int) []byte {
@@ -32,22 +35,36 @@ func randomKey(tb testing.TB, size ...int) []byte {
 	return bytes
 }
 
-func Test_NewBlake2B_KeyLengthPanicsAndBounds(t *testing.T) {
+func newSigner(tb testing.TB, algo string, key []byte, opts ...Options) *HMACSigner {
+	tb.Helper()
+
+	s, err := New(algo, key, opts...)
+	require.NoError(tb, err)
+
+	return s
+}
+
+func Test_New_KeyLengthAndAlgorithm(t *testing.T) {
 	t.Parallel()
 	// too short
-	require.Panics(t, func() { New("sha256", randomKey(t, 31)) })
+	_, err := New("sha256", randomKey(t, 31))
+	require.ErrorIs(t, err, ErrKeySize)
 	// too long
-	require.Panics(t, func() { New("sha256", randomKey(t, 65)) })
+	_, err = New("sha256", randomKey(t, 65))
+	require.ErrorIs(t, err, ErrKeySize)
 	// boundary 32 OK
-	_ = New("sha256", randomKey(t, 32))
+	_ = newSigner(t, "sha256", randomKey(t, 32))
 	// boundary 64 OK
-	_ = New("sha256", randomKey(t, 64))
+	_ = newSigner(t, "blake2b", randomKey(t, 64))
+	// unknown algorithms fail at construction instead of on the first Sign
+	_, err = New("md5", randomKey(t, 32))
+	require.ErrorIs(t, err, utils.ErrUnknownHasher)
 }
 
 func Test_Sign_And_Verify_Success_NoExpiration(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 
 	signed, err := s.Sign("https://example.com/path?hello=world", 0)
 	require.NoError(t, err)
@@ -64,7 +81,7 @@ func Test_Sign_And_Verify_Success_WithFutureExpiration(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 123000000, time.UTC)
 	dur := 2 * time.Hour
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 
 	// Sign should place expires = now + dur
 	signed, err := s.Sign("https://example.com/resource", dur)
@@ -85,7 +102,7 @@ func Test_Sign_And_Verify_Success_WithFutureExpiration(t *testing.T) {
 func Test_Verify_Expired(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 
 	// Create a signed URL with future expiration, then verify after time advanced beyond expiry
 	signed, err := s.Sign("https://example.com/asset", time.Minute)
@@ -100,21 +117,21 @@ func Test_Verify_Expired(t *testing.T) {
 func Test_Verify_MissingSignature(t *testing.T) {
 	t.Parallel()
 	u, _ := url.Parse("https://example.com/x?y=z")
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	require.Equal(t, ErrMissingSignature, s.Verify(u))
 }
 
 func Test_Verify_InvalidSignature_Base64(t *testing.T) {
 	t.Parallel()
 	u, _ := url.Parse("https://example.com/x?signature=***not_base64***")
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	require.Equal(t, ErrInvalidSignature, s.Verify(u))
 }
 
 func Test_Verify_InvalidSignature_Tampered(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 
 	signed, err := s.Sign("https://example.com/path?a=1", 0)
 	require.NoError(t, err)
@@ -130,7 +147,7 @@ func Test_Sign_FormatAndBase64(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 456000000, time.UTC)
 	dur := 90 * time.Minute
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 
 	signed, err := s.Sign("https://example.com/path", dur)
 	require.NoError(t, err)
@@ -148,31 +165,52 @@ func Test_Verify_InvalidExpires_ParseErrorBranch(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
 	key := randomKey(t, 32)
-	s := New("sha256", key, func() time.Time { return now })
+	s := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now }})
 
 	// Manually craft URL with invalid expires, then sign that exact URL string using hasher
 	u, _ := url.Parse("https://example.com/path?hello=world&" + Expiration + "=not-a-time")
 	h := s.hasher()
-	_, _ = h.Write([]byte(u.String()))
+	_, _ = h.Write([]byte(Canonical(u)))
 	sig := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
 	q := u.Query()
 	q.Set(Signature, sig)
 	u.RawQuery = q.Encode()
-	// Verify should attempt to parse expires and return a parsing error (not our custom errors)
+	// A validly signed but unparsable expires is reported, not a panic
 	err := s.Verify(u)
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrMalformedTime)
+	require.ErrorIs(t, err, ErrInvalidSignature)
 }
 
 func Test_Sign_InvalidURL_ReturnsError(t *testing.T) {
 	t.Parallel()
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	_, err := s.Sign("http://%zz", 0)
 	require.Error(t, err)
 }
 
+func Test_RelativePath_Rejected(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+	s := newSigner(t, "sha256", randomKey(t, 32))
+
+	_, err := s.Sign("report", 0)
+	req.ErrorIs(err, ErrRelativePath)
+
+	signed, err := s.Sign("/report", 0)
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.NoError(s.Verify(u))
+
+	u.Path = "report"
+	err = s.Verify(u)
+	req.ErrorIs(err, ErrInvalidSignature)
+	req.ErrorIs(err, ErrRelativePath)
+}
+
 func Test_Sign_NoQuery_NoDuration(t *testing.T) {
 	t.Parallel()
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	signed, err := s.Sign("https://example.com/plain", 0)
 	require.NoError(t, err)
 	u, err := url.Parse(signed)
@@ -185,7 +223,7 @@ func Test_Sign_NoQuery_NoDuration(t *testing.T) {
 func Test_Sign_Uses_DefaultNow(t *testing.T) {
 	t.Parallel()
 	dur := 5 * time.Second
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	signed, err := s.Sign("https://example.com/time", dur)
 	require.NoError(t, err)
 	u, err := url.Parse(signed)
@@ -200,7 +238,7 @@ func Test_Sign_WithQuery_WithDuration(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 21, 0, 0, 0, time.UTC)
 	dur := 10 * time.Minute
-	s := New("sha256", randomKey(t, 32), func() time.Time { return now })
+	s := newSigner(t, "sha256", randomKey(t, 32), Options{Now: func() time.Time { return now }})
 	signed, err := s.Sign("https://example.com/a?b=c", dur)
 	require.NoError(t, err)
 	u, err := url.Parse(signed)
@@ -213,7 +251,7 @@ func Test_Sign_WithQuery_WithDuration(t *testing.T) {
 func Test_Verify_MissingSignature_EmptyValue(t *testing.T) {
 	t.Parallel()
 	u, _ := url.Parse("https://example.com/x?signature=")
-	s := New("sha256", randomKey(t, 32))
+	s := newSigner(t, "sha256", randomKey(t, 32))
 	require.Equal(t, ErrMissingSignature, s.Verify(u))
 }
 
@@ -221,7 +259,7 @@ func Test_Verify_EmptyExpires_Value(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 22, 0, 0, 0, time.UTC)
 	key := randomKey(t, 32)
-	s := New("sha256", key, func() time.Time { return now })
+	s := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now }})
 	// create URL with empty expires but valid signature for that exact URL
 	u, _ := url.Parse("https://example.com/z?" + Expiration + "=")
 	h := s.hasher()
@@ -237,7 +275,7 @@ func Test_Verify_Expiration_EqualNow(t *testing.T) {
 	t.Parallel()
 	now := time.Date(2025, 8, 29, 23, 0, 0, 0, time.UTC)
 	key := randomKey(t, 32)
-	s := New("sha256", key, func() time.Time { return now })
+	s := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now }})
 	// Create URL with expires exactly at now
 	expires := url.QueryEscape(now.Format(time.RFC3339Nano))
 	u, _ := url.Parse("https://example.com/e?" + Expiration + "=" + expires)
@@ -250,3 +288,61 @@ func Test_Verify_Expiration_EqualNow(t *testing.T) {
 	// Since expires == now, it should NOT be considered expired
 	require.NoError(t, s.Verify(u))
 }
+
+func Test_Verify_NotBeforeAndClockSkew(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 0, time.UTC)
+	key := randomKey(t, 32)
+	at := func(offset time.Duration) Options {
+		return Options{Now: func() time.Time { return now.Add(offset) }, ClockSkew: 30 * time.Second}
+	}
+
+	signed, err := newSigner(t, "sha256", key, at(0)).Sign("https://example.com/launch", time.Hour, SignOptions{
+		NotBefore: now.Add(10 * time.Minute),
+	})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.NotEmpty(u.Query().Get(NotBefore))
+
+	for offset, expected := range map[time.Duration]error{
+		0:                               ErrNotYetValid,
+		10*time.Minute - time.Minute:    ErrNotYetValid,
+		10*time.Minute - 20*time.Second: nil,
+		30 * time.Minute:                nil,
+		time.Hour + 20*time.Second:      nil,
+		time.Hour + time.Minute:         ErrExpired,
+	} {
+		req.Equal(expected, newSigner(t, "sha256", key, at(offset)).Verify(u), offset)
+	}
+
+	// without skew the boundaries are exact
+	strict := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now.Add(time.Hour + time.Second) }})
+	req.Equal(ErrExpired, strict.Verify(u))
+}
+
+func Test_Sign_UnixExpiry(t *testing.T) {
+	t.Parallel()
+	req := require.New(t)
+
+	now := time.Date(2025, 8, 29, 20, 0, 0, 999000000, time.UTC)
+	key := randomKey(t, 32)
+
+	s := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now }, UnixExpiry: true})
+
+	signed, err := s.Sign("https://example.com/short", time.Hour, SignOptions{NotBefore: now})
+	req.NoError(err)
+
+	u, _ := url.Parse(signed)
+	req.Equal(strconv.FormatInt(now.Add(time.Hour).Unix(), 10), u.Query().Get(Expiration))
+	req.Equal(strconv.FormatInt(now.Unix(), 10), u.Query().Get(NotBefore))
+	req.NoError(s.Verify(u))
+
+	// verifiers accept either format whatever they sign with
+	req.NoError(newSigner(t, "sha256", key, Options{Now: func() time.Time { return now }}).Verify(u))
+
+	expired := newSigner(t, "sha256", key, Options{Now: func() time.Time { return now.Add(time.Hour) }})
+	req.Equal(ErrExpired, expired.Verify(u))
+}